@@ -0,0 +1,309 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bboxtest provides a fake Bbox API server, serving canned responses
+// for tests and demos without a physical Bbox.
+package bboxtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPassword is the admin password accepted by the server
+	DefaultPassword = "bboxtest"
+
+	// CookieName is the name of the session cookie of the Bbox API
+	CookieName = "BBOX_ID"
+
+	apiVersion = "/api/v1"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// routes maps the endpoints of the Bbox API to their fixture.
+var routes = map[string]string{
+	"/device":             "device.json",
+	"/device/cpu":         "device_cpu.json",
+	"/device/log":         "device_log.json",
+	"/device/mem":         "device_mem.json",
+	"/wan/ip":             "wan_ip.json",
+	"/wan/ip/stats":       "wan_ip_stats.json",
+	"/wan/ftth/stats":     "wan_ftth_stats.json",
+	"/wan/diags":          "wan_diags.json",
+	"/wan/xdsl":           "wan_xdsl.json",
+	"/wan/xdsl/stats":     "wan_xdsl_stats.json",
+	"/lan/ip":             "lan_ip.json",
+	"/lan/stats":          "lan_stats.json",
+	"/hosts":              "hosts.json",
+	"/dhcp":               "dhcp.json",
+	"/dhcp/clients":       "dhcp_clients.json",
+	"/dns/stats":          "dns_stats.json",
+	"/services":           "services.json",
+	"/iptv":               "iptv.json",
+	"/iptv/diags":         "iptv_diags.json",
+	"/firewall/rules":     "firewall_rules.json",
+	"/firewall/v6/rules":  "firewall_v6_rules.json",
+	"/nat/rules":          "nat_rules.json",
+	"/upnp/igd/rules":     "upnp_igd_rules.json",
+	"/usb":                "usb.json",
+	"/usb/storage":        "usb_storage.json",
+	"/voip":               "voip.json",
+	"/voip/fullcalllog/1": "voip_fullcalllog_1.json",
+	"/voip/fullcalllog/2": "voip_fullcalllog_2.json",
+	"/wireless":           "wireless.json",
+	"/wireless/24/stats":  "wireless_24_stats.json",
+	"/wireless/5/stats":   "wireless_5_stats.json",
+}
+
+// Fault defines how the server misbehaves on an endpoint
+type Fault struct {
+	// Latency delays the response
+	Latency time.Duration
+	// StatusCode replaces the status of the response, e.g. 500
+	StatusCode int
+	// Body replaces the fixture, e.g. with malformed JSON
+	Body string
+	// StringNumbers lists the fields whose numbers are sent as strings,
+	// like some firmwares do
+	StringNumbers []string
+}
+
+// Server is a fake Bbox API, enforcing the cookie authentication
+type Server struct {
+	*httptest.Server
+	Password string
+
+	mu        sync.Mutex
+	responses map[string][]byte
+	faults    map[string]Fault
+	sessions  map[string]bool
+	logins    int
+	requests  map[string]int
+}
+
+// NewServer starts a TLS server serving the fixtures. The caller must call Close.
+func NewServer() *Server {
+	s := &Server{
+		Password:  DefaultPassword,
+		responses: make(map[string][]byte),
+		faults:    make(map[string]Fault),
+		sessions:  make(map[string]bool),
+		requests:  make(map[string]int),
+	}
+	for endpoint, fixture := range routes {
+		s.responses[endpoint] = Fixture(fixture)
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Fixture returns the content of a canned response
+func Fixture(name string) []byte {
+	content, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic(fmt.Sprintf("bboxtest: unknown fixture %s", name))
+	}
+	return content
+}
+
+// SetResponse replaces the response of an endpoint, or adds a new endpoint
+func (s *Server) SetResponse(endpoint string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[endpoint] = body
+}
+
+// RemoveResponse makes an endpoint unknown, like on firmwares without it
+func (s *Server) RemoveResponse(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, endpoint)
+}
+
+// SetFault makes the server misbehave on the endpoint
+func (s *Server) SetFault(endpoint string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[endpoint] = fault
+}
+
+// ClearFaults makes the server behave again on all endpoints
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]Fault)
+}
+
+// ExpireSessions invalidates the session cookies, like a Bbox reboot
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+// Logins returns the number of successful authentications
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Requests returns the number of requests received on the endpoint
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiVersion) {
+		http.NotFound(w, r)
+		return
+	}
+	endpoint := strings.TrimPrefix(r.URL.Path, apiVersion)
+
+	s.mu.Lock()
+	s.requests[endpoint]++
+	fault := s.faults[endpoint]
+	s.mu.Unlock()
+
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault.StatusCode != 0 && fault.StatusCode != http.StatusOK {
+		writeException(w, fault.StatusCode, "internal", "error")
+		return
+	}
+
+	if endpoint == "/login" {
+		s.login(w, r)
+		return
+	}
+	if !s.authenticated(r) {
+		writeException(w, http.StatusUnauthorized, "session", "invalid")
+		return
+	}
+
+	s.mu.Lock()
+	body, ok := s.responses[endpoint]
+	s.mu.Unlock()
+	if !ok {
+		writeException(w, http.StatusNotFound, "api", "unknown")
+		return
+	}
+	if fault.Body != "" {
+		body = []byte(fault.Body)
+	} else if len(fault.StringNumbers) > 0 {
+		body = stringifyNumbers(body, fault.StringNumbers)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeException(w, http.StatusMethodNotAllowed, "method", "invalid")
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("password") != s.Password {
+		writeException(w, http.StatusUnauthorized, "password", "invalid")
+		return
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		writeException(w, http.StatusInternalServerError, "session", "error")
+		return
+	}
+	session := hex.EncodeToString(token)
+
+	s.mu.Lock()
+	s.sessions[session] = true
+	s.logins++
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: CookieName, Value: session, Path: "/"})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[cookie.Value]
+}
+
+// writeException writes an error like the Bbox API does.
+func writeException(w http.ResponseWriter, statusCode int, name string, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, `{"exception":{"domain":"api.bbox.lan","code":"%d","errors":[{"name":"%s","reason":"%s"}]}}`,
+		statusCode, name, reason)
+}
+
+// stringifyNumbers sends the numbers of the given fields as strings.
+func stringifyNumbers(body []byte, fields []string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var content interface{}
+	if err := dec.Decode(&content); err != nil {
+		return body
+	}
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		names[field] = true
+	}
+	content = stringify(content, names)
+	result, err := json.Marshal(content)
+	if err != nil {
+		return body
+	}
+	return result
+}
+
+func stringify(value interface{}, names map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if number, ok := field.(json.Number); ok && names[key] {
+				v[key] = number.String()
+			} else {
+				v[key] = stringify(field, names)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringify(item, names)
+		}
+	}
+	return value
+}
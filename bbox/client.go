@@ -17,13 +17,17 @@ package bbox
 import (
 	// "encoding/json"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 
 	// "io/ioutil"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log/level"
@@ -36,6 +40,8 @@ const (
 	// mediaType    = "application/json"
 
 	apiVersion = "/api/v1"
+
+	defaultTimeout = 10 * time.Second
 )
 
 // var (
@@ -43,36 +49,62 @@ const (
 // 	userAgent   = fmt.Sprintf("prom/%s", application)
 // )
 
-// Metrics define Bbox Prometheus metrics
-type Metrics struct {
-	Device    DeviceMetrics   `json:"device"`
-	Wan       WanMetrics      `json:"wan"`
-	Lan       LanMetrics      `json:"lan"`
-	DNS       DNSMetrics      `json:"dns"`
-	Services  ServicesMetrics `json:"services"`
-	FtthState string          `json:"ftth_state"`
-	Wireless  WirelessMetrics `json:"wireless"`
-	IPTV      IPTVMetrics     `json:"iptv"`
-}
-
+// Client is a client for the Bbox API
 type Client struct {
-	url      string
-	cookies  []*http.Cookie
-	password string
-	logger   log.Logger
+	url        string
+	password   string
+	timeout    time.Duration
+	tlsConfig  *tls.Config
+	transport  http.RoundTripper
+	httpClient *http.Client
+	retry      RetryPolicy
+	breaker    circuitBreaker
+	session    session
+	deviceLog  deviceLog
+	wanAddress wanAddress
+	wanLinks   wanLinks
+	logger     log.Logger
 }
 
-func NewClient(endpoint string, password string, logger log.Logger) (*Client, error) {
+// NewClient creates a client for the Bbox API, configured by the options.
+func NewClient(endpoint string, password string, logger log.Logger, opts ...Option) (*Client, error) {
 	url, err := url.Parse(endpoint)
-	if err != nil || url.Scheme != "https" {
+	if err != nil {
 		return nil, fmt.Errorf("invalid bbox address: %s", err)
 	}
-	level.Info(logger).Log("msg", "Create client", "endpoint", endpoint)
-	return &Client{
+	if url.Scheme != "https" {
+		return nil, fmt.Errorf("bbox address %s must use https", endpoint)
+	}
+	client := &Client{
 		url:      fmt.Sprintf("%s%s", url.String(), apiVersion),
 		password: password,
-		logger:   logger,
-	}, nil
+		timeout:  defaultTimeout,
+		retry:    DefaultRetryPolicy,
+		breaker: circuitBreaker{
+			failureThreshold: defaultFailureThreshold,
+			cooldown:         defaultCooldown,
+		},
+		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if client.httpClient == nil {
+		transport := client.transport
+		if transport == nil {
+			defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+			defaultTransport.TLSClientConfig = client.tlsConfig
+			transport = defaultTransport
+		}
+		client.httpClient = &http.Client{Timeout: client.timeout, Transport: transport}
+	}
+	level.Info(logger).Log("msg", "Create client", "endpoint", endpoint)
+	return client, nil
+}
+
+// Close releases the idle connections of the client
+func (client *Client) Close() {
+	client.httpClient.CloseIdleConnections()
 }
 
 // func (client *Client) setupHeaders(request *http.Request) {
@@ -82,130 +114,131 @@ func NewClient(endpoint string, password string, logger log.Logger) (*Client, er
 // 	request.Header.Add("User-Agent", userAgent)
 // }
 
-// GetMetrics retrieve available metrics for the API Router
-func (client *Client) GetMetrics() (*Metrics, error) {
-	level.Info(client.logger).Log("msg", "Get metrics")
-
-	var metrics Metrics
-
-	deviceMetrics, err := client.getDeviceMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("device metrics : %s", err)
-	}
-	level.Info(client.logger).Log("msg", "Device metrics", "metrics", deviceMetrics)
-	metrics.Device = *deviceMetrics
-
-	servicesMetrics, err := client.getServicesMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("services metrics: %s", err)
-	}
-	level.Info(client.logger).Log("msg", "Services metrics", "metrics", servicesMetrics)
-	metrics.Services = *servicesMetrics
-
-	wanMetrics, err := client.getWanMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("WAN metrics: %s", err)
-	}
-	level.Info(client.logger).Log("msg", "WAN metrics", "metrics", wanMetrics)
-	metrics.Wan = *wanMetrics
-
-	lanMetrics, err := client.getLanMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("LAN metrics: %s", err)
-	}
-	level.Info(client.logger).Log("msg", "LAN metrics: %#v", lanMetrics)
-	metrics.Lan = *lanMetrics
-
-	wirelessMetrics, err := client.getWirelessMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("wireless metrics %s", err)
-	}
-	level.Info(client.logger).Log("msg", "WIFI metrics", "metrics", wirelessMetrics)
-	metrics.Wireless = *wirelessMetrics
+// Authenticate log in the Bbox API and keeps the session cookie
+func (client *Client) Authenticate(ctx context.Context) error {
+	client.session.Lock()
+	defer client.session.Unlock()
+	return client.login(ctx)
+}
 
-	dnsMetrics, err := client.getDNSMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("dns metrics %s", err)
+// login must be called with the session lock held.
+func (client *Client) login(ctx context.Context) error {
+	err := client.doLogin(ctx)
+	if errors.Is(err, ErrCircuitOpen) {
+		// No request was sent to the Bbox
+		client.session.cookies = nil
+		return err
 	}
-	level.Info(client.logger).Log("msg", "DNS metrics", "metrics", dnsMetrics)
-	metrics.DNS = *dnsMetrics
-
-	iptv, err := client.getIPTVMetrics()
+	atomic.AddUint64(&client.session.loginAttempts, 1)
 	if err != nil {
-		return nil, fmt.Errorf("iptv metrics %s", err)
+		atomic.AddUint64(&client.session.loginFailures, 1)
+		client.session.cookies = nil
+		return err
 	}
-	level.Info(client.logger).Log("msg", "IPTV metrics", "metrics", fmt.Sprintf("%s", iptv))
-	metrics.IPTV = *iptv
-
-	return &metrics, nil
+	return nil
 }
 
-func (client *Client) Authenticate() error {
+func (client *Client) doLogin(ctx context.Context) error {
 	request := fmt.Sprintf("%s/login", client.url)
 	level.Info(client.logger).Log("msg", "API request", "api", request)
-	resp, err := http.Post(
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
 		request,
-		"application/x-www-form-urlencoded",
 		bytes.NewBuffer([]byte(fmt.Sprintf("password=%s", client.password))))
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.send(req)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	defer resp.Body.Close()
 	level.Info(client.logger).Log("msg", "API response", "api", request, "code", resp.StatusCode)
-	if resp.StatusCode > 300 {
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("authentication failed: %s", err)
-		}
-		dec := json.NewDecoder(bytes.NewBuffer(body))
-		var apiError APIError
-		if err := dec.Decode(&apiError); err != nil {
-			return fmt.Errorf("authentication failed: %s", err)
-		}
-		return fmt.Errorf("authentication failed: %+v", apiError)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	if err := checkResponse(resp.StatusCode, body); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
 	}
 	cookies := resp.Cookies()
 	if len(resp.Cookies()) == 0 {
 		return fmt.Errorf("can't retreive Cookie from API response")
 	}
-	client.cookies = cookies
+	client.session.cookies = cookies
+	client.session.id++
 	return nil
 }
 
-func (client *Client) apiRequest(request string, v interface{}) error {
+func (client *Client) apiRequest(ctx context.Context, request string, v interface{}) error {
 	url := fmt.Sprintf("%s%s", client.url, request)
-	level.Debug(client.logger).Log("msg", "API request", "request", url)
+	cookies, sessionID, err := client.getSession(ctx)
+	if err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	body, err := client.doRequestWithRetry(ctx, url, cookies)
+	if errors.Is(err, ErrUnauthorized) {
+		level.Info(client.logger).Log("msg", "Session expired", "request", url)
+		if cookies, err = client.renewSession(ctx, sessionID); err != nil {
+			return err
+		}
+		body, err = client.doRequestWithRetry(ctx, url, cookies)
+	}
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Cache-Control", "no-cache")
-	if client.cookies != nil {
-		for _, cookie := range client.cookies {
-			req.AddCookie(cookie)
+	level.Debug(client.logger).Log("msg", "API response value", "request", url, "content", string(body))
+	dec := json.NewDecoder(bytes.NewBuffer(body))
+	if err := dec.Decode(v); err != nil {
+		return &DecodeError{Endpoint: request, Err: err}
+	}
+	return nil
+}
+
+// doRequestWithRetry sends the GET request, retrying on transient errors
+// according to the retry policy.
+func (client *Client) doRequestWithRetry(ctx context.Context, url string, cookies []*http.Cookie) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		body, err := client.doRequest(ctx, url, cookies)
+		if err == nil || attempt >= client.retry.MaxAttempts || !isRetryable(err) {
+			return body, err
+		}
+		backoff := client.retry.backoff(attempt)
+		level.Debug(client.logger).Log("msg", "Retry API request", "request", url, "attempt", attempt, "backoff", backoff, "err", err)
+		if err := wait(ctx, backoff); err != nil {
+			return nil, err
 		}
 	}
+}
 
-	httpClient := &http.Client{Timeout: time.Second * 10}
-	resp, err := httpClient.Do(req)
+// doRequest sends the request and returns the body of the response,
+// or an APIError if the Bbox API rejected it.
+func (client *Client) doRequest(ctx context.Context, url string, cookies []*http.Cookie) ([]byte, error) {
+	level.Debug(client.logger).Log("msg", "API request", "request", url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	resp, err := client.send(req)
+	if err != nil {
+		return nil, err
 	}
-
 	defer resp.Body.Close()
 	level.Debug(client.logger).Log("msg", "API response check", "request", url, "code", resp.StatusCode)
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	level.Debug(client.logger).Log("msg", "API response value", "request", url, "content", string(body))
-	dec := json.NewDecoder(bytes.NewBuffer(body))
-	if err := dec.Decode(v); err != nil {
-		return err
+	if err := checkResponse(resp.StatusCode, body); err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
 	}
-	level.Info(client.logger).Log("msg", "API entity", "api", fmt.Sprintf("%+v", v))
-	return nil
+	return body, nil
 }
@@ -14,7 +14,13 @@
 
 package bbox
 
-import "github.com/go-kit/kit/log/level"
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+)
 
 type DeviceMetrics struct {
 	Informations []DeviceInformations `json:"informations"`
@@ -73,22 +79,23 @@ type DeviceCPU struct {
 	} `json:"device"`
 }
 
-func (client *Client) getDeviceMetrics() (*DeviceMetrics, error) {
+// GetDeviceMetrics returns the informations, CPU and memory of the Bbox
+func (client *Client) GetDeviceMetrics(ctx context.Context) (*DeviceMetrics, error) {
 	var deviceStats DeviceMetrics
 
-	informations, err := client.getDeviceInformations()
+	informations, err := client.getDeviceInformations(ctx)
 	if err != nil {
 		return nil, err
 	}
 	deviceStats.Informations = informations
 
-	cpu, err := client.getDeviceCPU()
+	cpu, err := client.getDeviceCPU(ctx)
 	if err != nil {
 		return nil, err
 	}
 	deviceStats.CPU = cpu
 
-	memory, err := client.getDeviceMemory()
+	memory, err := client.getDeviceMemory(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -97,12 +104,66 @@ func (client *Client) getDeviceMetrics() (*DeviceMetrics, error) {
 	return &deviceStats, nil
 }
 
+// GetDeviceInformations returns the informations of the Bbox, like the
+// technologies used by the WAN
+func (client *Client) GetDeviceInformations(ctx context.Context) (*DeviceInformations, error) {
+	informations, err := client.getDeviceInformations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(informations) == 0 {
+		return nil, fmt.Errorf("no device informations")
+	}
+	return &informations[0], nil
+}
+
+// WanLinks are the technologies used by the WAN of the Bbox
+type WanLinks struct {
+	ADSL bool
+	VDSL bool
+	FTTH bool
+}
+
+// wanLinks keeps the technologies used by the WAN for the session they were
+// retrieved with.
+type wanLinks struct {
+	sync.Mutex
+	session int
+	links   *WanLinks
+}
+
+// GetWanLinks returns the technologies used by the WAN. They are retrieved once
+// per session, as they don't change until the Bbox restarts.
+func (client *Client) GetWanLinks(ctx context.Context) (WanLinks, error) {
+	_, sessionID, err := client.getSession(ctx)
+	if err != nil {
+		return WanLinks{}, err
+	}
+	client.wanLinks.Lock()
+	defer client.wanLinks.Unlock()
+	if client.wanLinks.links != nil && client.wanLinks.session == sessionID {
+		return *client.wanLinks.links, nil
+	}
+	device, err := client.GetDeviceInformations(ctx)
+	if err != nil {
+		return WanLinks{}, err
+	}
+	links := WanLinks{
+		ADSL: device.Device.Using.ADSL != 0,
+		VDSL: device.Device.Using.VDSL != 0,
+		FTTH: device.Device.Using.FTTH != 0,
+	}
+	client.wanLinks.session = sessionID
+	client.wanLinks.links = &links
+	return links, nil
+}
+
 // getDeviceInformations returns Bbox information
 // See: https://api.bbox.fr/doc/apirouter/#api-Device-GetDevice
-func (client *Client) getDeviceInformations() ([]DeviceInformations, error) {
+func (client *Client) getDeviceInformations(ctx context.Context) ([]DeviceInformations, error) {
 	level.Info(client.logger).Log("msg", "Retrieve device informations")
 	var informations []DeviceInformations
-	if err := client.apiRequest("/device", &informations); err != nil {
+	if err := client.apiRequest(ctx, "/device", &informations); err != nil {
 		return nil, err
 	}
 	return informations, nil
@@ -110,10 +171,10 @@ func (client *Client) getDeviceInformations() ([]DeviceInformations, error) {
 
 // getDeviceCPU returns Bbox CPU information
 // See: https://api.bbox.fr/doc/apirouter/#api-Device-GetDeviceCPU
-func (client *Client) getDeviceCPU() ([]DeviceCPU, error) {
+func (client *Client) getDeviceCPU(ctx context.Context) ([]DeviceCPU, error) {
 	level.Info(client.logger).Log("msg", "Retrieve device CPU")
 	var cpu []DeviceCPU
-	if err := client.apiRequest("/device/cpu", &cpu); err != nil {
+	if err := client.apiRequest(ctx, "/device/cpu", &cpu); err != nil {
 		return nil, err
 	}
 	return cpu, nil
@@ -121,10 +182,10 @@ func (client *Client) getDeviceCPU() ([]DeviceCPU, error) {
 
 // getDeviceMemory returns Bbox Memory information
 // See: https://api.bbox.fr/doc/apirouter/#api-Device-GetDeviceMem
-func (client *Client) getDeviceMemory() ([]DeviceMemory, error) {
+func (client *Client) getDeviceMemory(ctx context.Context) ([]DeviceMemory, error) {
 	level.Info(client.logger).Log("msg", "Retrieve device memory")
 	var memory []DeviceMemory
-	if err := client.apiRequest("/device/mem", &memory); err != nil {
+	if err := client.apiRequest(ctx, "/device/mem", &memory); err != nil {
 		return nil, err
 	}
 	return memory, nil
@@ -0,0 +1,55 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+)
+
+func TestGetWanLinks(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	links, err := client.GetWanLinks(ctx)
+	if err != nil {
+		t.Fatalf("can't get WAN links: %s", err)
+	}
+	if !links.FTTH || links.ADSL || links.VDSL {
+		t.Errorf("unexpected WAN links: %+v", links)
+	}
+	if _, err := client.GetWanLinks(ctx); err != nil {
+		t.Fatalf("can't get WAN links: %s", err)
+	}
+	if requests := server.Requests("/device"); requests != 1 {
+		t.Errorf("expected 1 request for the device, got %d", requests)
+	}
+
+	// A new session, e.g. after a restart of the Bbox, retrieves them again
+	server.ExpireSessions()
+	if _, err := client.GetWanMetrics(ctx); err != nil {
+		t.Fatalf("can't get WAN metrics: %s", err)
+	}
+	if _, err := client.GetWanLinks(ctx); err != nil {
+		t.Fatalf("can't get WAN links: %s", err)
+	}
+	if requests := server.Requests("/device"); requests != 2 {
+		t.Errorf("expected 2 requests for the device, got %d", requests)
+	}
+}
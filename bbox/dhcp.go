@@ -13,3 +13,90 @@
 // limitations under the License.
 
 package bbox
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+type DhcpMetrics struct {
+	Informations []DhcpInformations
+	Clients      []DhcpClient
+	Hosts        []LanHost
+}
+
+// DhcpInformations represents the configuration of the DHCP server of the Bbox
+type DhcpInformations struct {
+	Dhcp struct {
+		State      string  `json:"state"`
+		Enable     flexInt `json:"enable"`
+		MinAddress string  `json:"minaddress"`
+		MaxAddress string  `json:"maxaddress"`
+		Lease      flexInt `json:"bail"` // Lease time in seconds
+	} `json:"dhcp"`
+}
+
+// DhcpClient represents a static lease of the DHCP server
+type DhcpClient struct {
+	ID         flexInt `json:"id"`
+	Enable     flexInt `json:"enable"`
+	Hostname   string  `json:"hostname"`
+	Macaddress string  `json:"macaddress"`
+	Ipaddress  string  `json:"ipaddress"`
+}
+
+// GetDhcpMetrics returns the configuration and the static leases of the DHCP
+// server, with the devices of the LAN to find the dynamic leases
+func (client *Client) GetDhcpMetrics(ctx context.Context) (*DhcpMetrics, error) {
+	var metrics DhcpMetrics
+
+	informations, err := client.getDhcpInformations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Informations = informations
+
+	clients, err := client.getDhcpClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Clients = clients
+
+	hosts, err := client.GetLanHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Hosts = hosts
+
+	return &metrics, nil
+}
+
+// getDhcpInformations returns the configuration of the DHCP server
+// See: https://api.bbox.fr/doc/apirouter/#api-DHCP-GetDHCP
+func (client *Client) getDhcpInformations(ctx context.Context) ([]DhcpInformations, error) {
+	level.Info(client.logger).Log("msg", "Retrieve DHCP informations from Bbox")
+	var informations []DhcpInformations
+	if err := client.apiRequest(ctx, "/dhcp", &informations); err != nil {
+		return nil, err
+	}
+	return informations, nil
+}
+
+// getDhcpClients returns the static leases of the DHCP server
+// See: https://api.bbox.fr/doc/apirouter/#api-DHCP-GetDHCPClients
+func (client *Client) getDhcpClients(ctx context.Context) ([]DhcpClient, error) {
+	level.Info(client.logger).Log("msg", "Retrieve DHCP clients from Bbox")
+	var response []struct {
+		Dhcp struct {
+			Clients []DhcpClient `json:"clients"`
+		} `json:"dhcp"`
+	}
+	if err := client.apiRequest(ctx, "/dhcp/clients", &response); err != nil {
+		return nil, err
+	}
+	if len(response) == 0 {
+		return nil, nil
+	}
+	return response[0].Dhcp.Clients, nil
+}
@@ -14,7 +14,11 @@
 
 package bbox
 
-import "github.com/go-kit/kit/log/level"
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+)
 
 type DNSMetrics struct {
 	Principal []DNSAverage `json:"principal"`
@@ -29,10 +33,11 @@ type DNSAverage struct {
 	} `json:"dns"`
 }
 
-func (client *Client) getDNSMetrics() (*DNSMetrics, error) {
+// GetDNSMetrics returns the DNS statistics
+func (client *Client) GetDNSMetrics(ctx context.Context) (*DNSMetrics, error) {
 	var metrics DNSMetrics
 
-	dns, err := client.getDNSAverage()
+	dns, err := client.getDNSAverage(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +48,10 @@ func (client *Client) getDNSMetrics() (*DNSMetrics, error) {
 
 // getDNSAverage returns information about dns average.
 // See: https://api.bbox.fr/doc/apirouter/#api-DNS-GetDNS
-func (client *Client) getDNSAverage() ([]DNSAverage, error) {
+func (client *Client) getDNSAverage(ctx context.Context) ([]DNSAverage, error) {
 	level.Info(client.logger).Log("msg", "Retrieve DNS informations")
 	var dns []DNSAverage
-	if err := client.apiRequest("/dns/stats", &dns); err != nil {
+	if err := client.apiRequest(ctx, "/dns/stats", &dns); err != nil {
 		return nil, err
 	}
 	return dns, nil
@@ -14,13 +14,108 @@
 
 package bbox
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is returned when the Bbox API rejects the password or the session
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited is returned when the Bbox API rejects too many requests
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotFound is returned when the endpoint doesn't exist on the firmware of the Bbox
+	ErrNotFound = errors.New("not found")
+)
+
+// APIError is an exception returned by the Bbox API
 type APIError struct {
-	Exception struct {
-		Domain string `json:"domain"`
-		Code   string `json:"code"`
-		Errors []struct {
-			Name   string `json:"name"`
-			Reason string `json:"reason"`
-		} `json:"errors"`
-	} `json:"exception"`
+	StatusCode int
+	Domain     string
+	Code       string
+	Reasons    []APIErrorReason
+}
+
+// APIErrorReason describes why a parameter was rejected by the Bbox API
+type APIErrorReason struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, reading the
+// exception sent by the Bbox API.
+func (e *APIError) UnmarshalJSON(b []byte) error {
+	var body struct {
+		Exception *struct {
+			Domain string           `json:"domain"`
+			Code   string           `json:"code"`
+			Errors []APIErrorReason `json:"errors"`
+		} `json:"exception"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return err
+	}
+	if body.Exception == nil {
+		return fmt.Errorf("no exception in response")
+	}
+	e.Domain = body.Exception.Domain
+	e.Code = body.Exception.Code
+	e.Reasons = body.Exception.Errors
+	return nil
+}
+
+func (e *APIError) Error() string {
+	reasons := make([]string, 0, len(e.Reasons))
+	for _, reason := range e.Reasons {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", reason.Name, reason.Reason))
+	}
+	return fmt.Sprintf("api error (status %d, domain %q, code %q): %s",
+		e.StatusCode, e.Domain, e.Code, strings.Join(reasons, ", "))
+}
+
+// Is makes errors.Is match ErrUnauthorized, ErrRateLimited and ErrNotFound from the status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.Code == strconv.Itoa(http.StatusUnauthorized)
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || e.Code == strconv.Itoa(http.StatusTooManyRequests)
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.Code == strconv.Itoa(http.StatusNotFound)
+	}
+	return false
+}
+
+// DecodeError is returned when the response of the Bbox API can't be parsed
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("can't decode response of %s: %s", e.Endpoint, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// checkResponse returns an APIError if the request failed, or if the Bbox API
+// sent an exception instead of the expected content.
+func checkResponse(statusCode int, body []byte) error {
+	var apiError APIError
+	isException := bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) && json.Unmarshal(body, &apiError) == nil
+	if statusCode >= 200 && statusCode < 300 && !isException {
+		return nil
+	}
+	apiError.StatusCode = statusCode
+	if !isException {
+		apiError.Code = strconv.Itoa(statusCode)
+	}
+	return &apiError
 }
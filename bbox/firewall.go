@@ -0,0 +1,133 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// RulesMetrics define the firewall rules and the port forwardings of the Bbox
+type RulesMetrics struct {
+	FirewallRules   []FirewallRule
+	FirewallV6Rules []FirewallRule
+	NatRules        []NatRule
+	UpnpRules       []NatRule
+}
+
+// FirewallRule represents a rule of the IPv4 or IPv6 firewall
+type FirewallRule struct {
+	ID          flexInt `json:"id"`
+	Enable      flexInt `json:"enable"`
+	Description string  `json:"description"`
+	Action      string  `json:"action"`
+	SrcIP       string  `json:"srcip"`
+	DstIP       string  `json:"dstip"`
+	SrcPorts    string  `json:"srcports"`
+	DstPorts    string  `json:"dstports"`
+	Order       flexInt `json:"order"`
+	Protocols   string  `json:"protocols"`
+	IPProtocol  string  `json:"ipprotocol"`
+	// Number of packets matching the rule, nil if the firmware doesn't count them
+	Utilisation *flexInt `json:"utilisation"`
+}
+
+// NatRule represents a port forwarding, configured manually or by UPnP
+type NatRule struct {
+	ID           flexInt `json:"id"`
+	Enable       flexInt `json:"enable"`
+	Description  string  `json:"description"`
+	Protocol     string  `json:"protocol"`
+	ExternalIP   string  `json:"externalip"`
+	ExternalPort string  `json:"externalport"`
+	InternalIP   string  `json:"internalip"`
+	InternalPort string  `json:"internalport"`
+	// Remaining time of an UPnP port forwarding in seconds
+	Expire flexInt `json:"expire"`
+}
+
+// GetRulesMetrics returns the rules of the firewall and the port forwardings.
+// Rules of an endpoint unknown on the firmware of the Bbox are empty.
+func (client *Client) GetRulesMetrics(ctx context.Context) (*RulesMetrics, error) {
+	var metrics RulesMetrics
+
+	var firewall []struct {
+		Firewall struct {
+			Rules []FirewallRule `json:"rules"`
+		} `json:"firewall"`
+	}
+	if err := client.getRules(ctx, "/firewall/rules", &firewall); err != nil {
+		return nil, err
+	}
+	if len(firewall) > 0 {
+		metrics.FirewallRules = firewall[0].Firewall.Rules
+	}
+
+	var firewallV6 []struct {
+		Firewall struct {
+			Rules []FirewallRule `json:"rules"`
+		} `json:"firewall"`
+	}
+	if err := client.getRules(ctx, "/firewall/v6/rules", &firewallV6); err != nil {
+		return nil, err
+	}
+	if len(firewallV6) > 0 {
+		metrics.FirewallV6Rules = firewallV6[0].Firewall.Rules
+	}
+
+	var nat []struct {
+		Nat struct {
+			Rules []NatRule `json:"rules"`
+		} `json:"nat"`
+	}
+	if err := client.getRules(ctx, "/nat/rules", &nat); err != nil {
+		return nil, err
+	}
+	if len(nat) > 0 {
+		metrics.NatRules = nat[0].Nat.Rules
+	}
+
+	var upnp []struct {
+		Upnp struct {
+			Igd struct {
+				Rules []NatRule `json:"rules"`
+			} `json:"igd"`
+		} `json:"upnp"`
+	}
+	if err := client.getRules(ctx, "/upnp/igd/rules", &upnp); err != nil {
+		return nil, err
+	}
+	if len(upnp) > 0 {
+		metrics.UpnpRules = upnp[0].Upnp.Igd.Rules
+	}
+
+	return &metrics, nil
+}
+
+// getRules reads the rules of an endpoint, ignoring an endpoint unknown on the firmware.
+// See: https://api.bbox.fr/doc/apirouter/#api-Firewall-GetFirewallRules
+func (client *Client) getRules(ctx context.Context, endpoint string, v interface{}) error {
+	level.Info(client.logger).Log("msg", "Retrieve rules from Bbox", "endpoint", endpoint)
+	if err := client.apiRequest(ctx, endpoint, v); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No rules on Bbox", "endpoint", endpoint)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
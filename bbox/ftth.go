@@ -0,0 +1,51 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// FtthStatistics represents the state of the FTTH optical link.
+// Optical values are unset when the firmware doesn't expose them.
+type FtthStatistics struct {
+	Wan struct {
+		Ftth struct {
+			Mode     string `json:"mode"`
+			State    string `json:"state"`
+			OntState string `json:"ont_state"` // GPON activation state, from O1 to O7
+			SFP      struct {
+				RxPower     optionalFloat `json:"rx_power"`    // dBm
+				TxPower     optionalFloat `json:"tx_power"`    // dBm
+				Temperature optionalFloat `json:"temperature"` // °C
+				Voltage     optionalFloat `json:"voltage"`     // V
+				Bias        optionalFloat `json:"bias"`        // mA
+			} `json:"sfp"`
+		} `json:"ftth"`
+	} `json:"wan"`
+}
+
+// GetFtthMetrics returns the state of the FTTH optical link
+// See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetFTTHStats
+func (client *Client) GetFtthMetrics(ctx context.Context) ([]FtthStatistics, error) {
+	level.Info(client.logger).Log("msg", "Retrieve FTTH metrics from Bbox")
+	var metrics []FtthStatistics
+	if err := client.apiRequest(ctx, "/wan/ftth/stats", &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -14,11 +14,16 @@
 
 package bbox
 
-import "github.com/go-kit/kit/log/level"
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/log/level"
+)
 
 type IPTVMetrics struct {
 	Informations []IPTVInformations `json:"informations"`
-	// Diagnostics  []IPTVDiagnostics  `json:"diagnostics"`
+	Diagnostics  []IPTVDiagnostics  `json:"diagnostics"`
 }
 
 type IPTVInformations struct {
@@ -34,40 +39,74 @@ type IPTVInformations struct {
 		// The channel name
 		Name string `json:"name"`
 		// the channel number
-		Number string `json:"number"`
+		Number flexInt `json:"number"`
 		// Defines if the channel is really received or not
-		Receipt string `json:"receipt"`
+		Receipt flexInt `json:"receipt"`
 		// Channel Id in the epg
 		Epgid string `json:"epgid"`
 	} `json:"iptv"`
 	Now string `json:"now"`
 }
 
-func (client *Client) getIPTVMetrics() (*IPTVMetrics, error) {
+// IPTVDiagnostics represents the state of the multicast groups of the IP TV
+type IPTVDiagnostics struct {
+	IPTV struct {
+		Diags struct {
+			Status    string `json:"status"`
+			Multicast []struct {
+				// the IP Address of the multicast
+				Address string `json:"address"`
+				// the channel number
+				Number flexInt `json:"number"`
+				// "Joined" if the IGMP join of the group succeeded
+				State string `json:"state"`
+				// Number of reception errors on the group
+				Errors flexInt `json:"errors"`
+			} `json:"multicast"`
+		} `json:"diags"`
+	} `json:"iptv"`
+}
+
+// GetIPTVMetrics returns the IP TV informations and diagnostics. The
+// diagnostics are empty on a firmware without them.
+func (client *Client) GetIPTVMetrics(ctx context.Context) (*IPTVMetrics, error) {
 	var metrics IPTVMetrics
 
-	informations, err := client.getIPTVInformations()
+	informations, err := client.getIPTVInformations(ctx)
 	if err != nil {
 		return nil, err
 	}
 	metrics.Informations = informations
 
+	diagnostics, err := client.getIPTVDiagnostics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Diagnostics = diagnostics
+
 	return &metrics, nil
 }
 
-func (client *Client) getIPTVInformations() ([]IPTVInformations, error) {
+func (client *Client) getIPTVInformations(ctx context.Context) ([]IPTVInformations, error) {
 	level.Info(client.logger).Log("msg", "Retrieve IP TV informations")
 	var iptvInformations []IPTVInformations
-	if err := client.apiRequest("/iptv", &iptvInformations); err != nil {
+	if err := client.apiRequest(ctx, "/iptv", &iptvInformations); err != nil {
 		return nil, err
 	}
 	return iptvInformations, nil
 }
 
-// func (client *Client) getIPTVDiagnostic() ([]IPTVInformations, error) {
-// 	level.Info(client.logger).Log("msg", "Retrieve IP TV diagnostic")
-// 	if err := client.apiRequest("/iptv/diags", nil); err != nil {
-// 		return nil, err
-// 	}
-// 	return nil, nil
-// }
+// getIPTVDiagnostics returns the state of the multicast groups
+// See: https://api.bbox.fr/doc/apirouter/#api-IPTV-GetIPTVDiags
+func (client *Client) getIPTVDiagnostics(ctx context.Context) ([]IPTVDiagnostics, error) {
+	level.Info(client.logger).Log("msg", "Retrieve IP TV diagnostics")
+	var diagnostics []IPTVDiagnostics
+	if err := client.apiRequest(ctx, "/iptv/diags", &diagnostics); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No IP TV diagnostics on Bbox")
+			return nil, nil
+		}
+		return nil, err
+	}
+	return diagnostics, nil
+}
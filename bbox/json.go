@@ -15,8 +15,12 @@
 package bbox
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // WTF The bbox API send result in string and/or int :(
@@ -24,23 +28,88 @@ import (
 // A FlexInt is an int that can be unmarshalled from a JSON field
 // that has either a number or a string value.
 // E.g. if the json field contains an string "42", the
-// FlexInt value will be "42".
+// FlexInt value will be "42". An empty string or null is 0, and
+// the decimals of a float are dropped.
 type flexInt int
 
 // UnmarshalJSON implements the json.Unmarshaler interface, which
 // allows us to ingest values of any json type as an int and run our custom conversion
 func (fi *flexInt) UnmarshalJSON(b []byte) error {
-	if b[0] != '"' {
-		return json.Unmarshal(b, (*int)(fi))
-	}
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
+	number, err := numberLiteral(b)
+	if err != nil || number == "" {
+		*fi = 0
 		return err
 	}
-	i, err := strconv.Atoi(s)
+	i, err := strconv.ParseInt(number, 10, 64)
 	if err != nil {
-		return err
+		f, ferr := strconv.ParseFloat(number, 64)
+		if ferr != nil || math.IsNaN(f) || f >= math.MaxInt64 || f <= math.MinInt64 {
+			return fmt.Errorf("can't decode %s as a number", b)
+		}
+		i = int64(f)
 	}
 	*fi = flexInt(i)
 	return nil
 }
+
+// A flexFloat is a float64 that can be unmarshalled from a JSON field
+// that has either a number or a string value. An empty string or null is 0.
+type flexFloat float64
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ff *flexFloat) UnmarshalJSON(b []byte) error {
+	number, err := numberLiteral(b)
+	if err != nil || number == "" {
+		*ff = 0
+		return err
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("can't decode %s as a number", b)
+	}
+	*ff = flexFloat(f)
+	return nil
+}
+
+// An optionalFloat is a flexFloat which may be absent. It is unset for a
+// missing field, null or an empty string, the firmwares sending any of them.
+type optionalFloat struct {
+	value flexFloat
+	set   bool
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (of *optionalFloat) UnmarshalJSON(b []byte) error {
+	number, err := numberLiteral(b)
+	if err != nil || number == "" {
+		*of = optionalFloat{}
+		return err
+	}
+	if err := of.value.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	of.set = true
+	return nil
+}
+
+// Value returns the value, and whether it is set.
+func (of optionalFloat) Value() (float64, bool) {
+	return float64(of.value), of.set
+}
+
+// numberLiteral returns the number of a JSON field, unquoting a string.
+// It is empty for an empty string or null.
+func numberLiteral(b []byte) (string, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return "", nil
+	}
+	if b[0] != '"' {
+		return string(b), nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(s), nil
+}
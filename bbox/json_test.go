@@ -0,0 +1,189 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+var flexIntTests = []struct {
+	input   string
+	want    flexInt
+	wantErr bool
+}{
+	{input: `42`, want: 42},
+	{input: `"42"`, want: 42},
+	{input: `-76`, want: -76},
+	{input: `"-76"`, want: -76},
+	{input: `" 12 "`, want: 12},
+	{input: `0`, want: 0},
+	{input: `""`, want: 0},
+	{input: `null`, want: 0},
+	{input: `12.0`, want: 12},
+	{input: `"12.7"`, want: 12},
+	{input: `1e3`, want: 1000},
+	{input: `118392734712`, want: 118392734712},
+	{input: `"abc"`, wantErr: true},
+	{input: `"NaN"`, wantErr: true},
+	{input: `"1e30"`, wantErr: true},
+	{input: `true`, wantErr: true},
+	{input: `{}`, wantErr: true},
+	{input: `[]`, wantErr: true},
+}
+
+func TestFlexInt(t *testing.T) {
+	for _, tt := range flexIntTests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got flexInt
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+var flexFloatTests = []struct {
+	input   string
+	want    flexFloat
+	wantErr bool
+}{
+	{input: `866`, want: 866},
+	{input: `"866"`, want: 866},
+	{input: `"144.4"`, want: 144.4},
+	{input: `-61.5`, want: -61.5},
+	{input: `""`, want: 0},
+	{input: `null`, want: 0},
+	{input: `"fast"`, wantErr: true},
+	{input: `"NaN"`, wantErr: true},
+	{input: `"-Inf"`, wantErr: true},
+	{input: `1e400`, wantErr: true},
+	{input: `false`, wantErr: true},
+}
+
+func TestFlexFloat(t *testing.T) {
+	for _, tt := range flexFloatTests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got flexFloat
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionalFloat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantSet bool
+		wantErr bool
+	}{
+		{input: `{"value": -21.5}`, want: -21.5, wantSet: true},
+		{input: `{"value": "-21.5"}`, want: -21.5, wantSet: true},
+		{input: `{"value": 0}`, want: 0, wantSet: true},
+		{input: `{"value": "0"}`, want: 0, wantSet: true},
+		{input: `{"value": ""}`},
+		{input: `{"value": " "}`},
+		{input: `{"value": null}`},
+		{input: `{}`},
+		{input: `{"value": "n/a"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got struct {
+				Value optionalFloat `json:"value"`
+			}
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if value, set := got.Value.Value(); value != tt.want || set != tt.wantSet {
+				t.Errorf("got %v (set: %t), want %v (set: %t)", value, set, tt.want, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestFlexIntQuoted(t *testing.T) {
+	// A number sent as a string decodes like the number itself
+	for _, input := range []string{`42`, `-76`, `0`, `12.5`, `-12.5`, `1e3`, `118392734712`} {
+		t.Run(input, func(t *testing.T) {
+			var i flexInt
+			if err := json.Unmarshal([]byte(input), &i); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			quoted := strconv.Quote(input)
+			var again flexInt
+			if err := json.Unmarshal([]byte(quoted), &again); err != nil || again != i {
+				t.Errorf("%s decodes to %d, but %s decodes to %d (%v)", input, i, quoted, again, err)
+			}
+		})
+	}
+}
+
+func FuzzFlexInt(f *testing.F) {
+	for _, tt := range flexIntTests {
+		f.Add([]byte(tt.input))
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var i flexInt
+		if err := json.Unmarshal(input, &i); err != nil {
+			return
+		}
+		// A decoded value encodes to the same number
+		b, err := json.Marshal(i)
+		if err != nil {
+			t.Fatalf("can't encode %d: %v", i, err)
+		}
+		var again flexInt
+		if err := json.Unmarshal(b, &again); err != nil || again != i {
+			t.Errorf("%s decodes to %d, but encodes to %s which decodes to %d (%v)", input, i, b, again, err)
+		}
+	})
+}
+
+func FuzzFlexFloat(f *testing.F) {
+	for _, tt := range flexFloatTests {
+		f.Add([]byte(tt.input))
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var ff flexFloat
+		if err := json.Unmarshal(input, &ff); err != nil {
+			return
+		}
+		// A decoded value encodes to the same number
+		b, err := json.Marshal(ff)
+		if err != nil {
+			t.Fatalf("can't encode %v: %v", ff, err)
+		}
+		var again flexFloat
+		if err := json.Unmarshal(b, &again); err != nil || again != ff {
+			t.Errorf("%s decodes to %v, but encodes to %s which decodes to %v (%v)", input, ff, b, again, err)
+		}
+	})
+}
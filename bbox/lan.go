@@ -15,6 +15,9 @@
 package bbox
 
 import (
+	"context"
+	"errors"
+
 	"github.com/go-kit/kit/log/level"
 )
 
@@ -30,61 +33,77 @@ type LanDevice struct {
 	} `json:"hosts"`
 }
 
+// LanHost represents a device known by the Bbox
 type LanHost struct {
-	ID         int           `json:"id"`
-	Hostname   string        `json:"hostname"`
-	Macaddress string        `json:"macaddress"`
-	Ipaddress  string        `json:"ipaddress"`
-	Type       string        `json:"type"`
-	Link       string        `json:"link"`
-	Devicetype string        `json:"devicetype"`
-	Firstseen  string        `json:"firstseen"`
-	Lastseen   interface{}   `json:"lastseen"`
-	IP6Address []interface{} `json:"ip6address"`
+	ID         flexInt             `json:"id"`
+	Hostname   string              `json:"hostname"`
+	Macaddress string              `json:"macaddress"`
+	Ipaddress  string              `json:"ipaddress"`
+	Type       string              `json:"type"`
+	Link       string              `json:"link"`
+	Devicetype string              `json:"devicetype"`
+	Firstseen  string              `json:"firstseen"`
+	Lastseen   flexInt             `json:"lastseen"` // Seconds since the device was seen
+	IP6Address []LanHostIP6Address `json:"ip6address"`
 	Ethernet   struct {
-		Physicalport int    `json:"physicalport"`
-		Logicalport  int    `json:"logicalport"`
-		Speed        string `json:"speed"`
-		Mode         string `json:"mode"`
+		Physicalport flexInt `json:"physicalport"`
+		Logicalport  flexInt `json:"logicalport"`
+		Speed        flexInt `json:"speed"` // Mbps, "" if not connected by Ethernet
+		Mode         string  `json:"mode"`
 	} `json:"ethernet"`
 	Stb struct {
 		Product string `json:"product"`
 		Serial  string `json:"serial"`
 	} `json:"stb,omitempty"`
 	Wireless struct {
-		Band       string      `json:"band"`
-		Rssi0      interface{} `json:"rssi0"` // String or int ? "rssi0":"-76","rssi1":0,"rssi2":0
-		Rssi1      interface{} `json:"rssi1"`
-		Rssi2      interface{} `json:"rssi2"`
-		Mcs        interface{} `json:"mcs"` // Same string or int ??
-		Rate       interface{} `json:"rate"`
-		Idle       interface{} `json:"idle"`
-		Wexindex   interface{} `json:"wexindex"`
-		Starealmac interface{} `json:"starealmac"`
+		Band       string    `json:"band"`
+		Rssi0      flexInt   `json:"rssi0"` // String or int: "rssi0":"-76","rssi1":0,"rssi2":0
+		Rssi1      flexInt   `json:"rssi1"`
+		Rssi2      flexInt   `json:"rssi2"`
+		Mcs        flexInt   `json:"mcs"`
+		Rate       flexFloat `json:"rate"`
+		Idle       flexInt   `json:"idle"`
+		Wexindex   flexInt   `json:"wexindex"`
+		Starealmac string    `json:"starealmac"`
 	} `json:"wireless"`
 	Plc struct {
-		Rxphyrate        string `json:"rxphyrate"`
-		Txphyrate        string `json:"txphyrate"`
-		Associateddevice int    `json:"associateddevice"`
-		Interface        int    `json:"interface"`
-		Ethernetspeed    int    `json:"ethernetspeed"`
+		Rxphyrate        flexFloat `json:"rxphyrate"`
+		Txphyrate        flexFloat `json:"txphyrate"`
+		Associateddevice flexInt   `json:"associateddevice"`
+		Interface        flexInt   `json:"interface"`
+		Ethernetspeed    flexInt   `json:"ethernetspeed"`
 	} `json:"plc"`
 	Lease           flexInt `json:"lease"`
-	Active          int     `json:"active"`
+	Active          flexInt `json:"active"`
 	Parentalcontrol struct {
-		Enable          int    `json:"enable"`
-		Status          string `json:"status"`
-		StatusRemaining int    `json:"statusRemaining"`
-		StatusUntil     string `json:"statusUntil"`
+		Enable          flexInt `json:"enable"`
+		Status          string  `json:"status"`
+		StatusRemaining flexInt `json:"statusRemaining"`
+		StatusUntil     string  `json:"statusUntil"`
 	} `json:"parentalcontrol"`
 	Ping struct {
-		Average int `json:"average"`
+		Average flexFloat `json:"average"`
 	} `json:"ping"`
 	Scan struct {
-		Services []interface{} `json:"services"`
+		Services []LanHostService `json:"services"`
 	} `json:"scan"`
 }
 
+// LanHostIP6Address represents an IPv6 address of a device
+type LanHostIP6Address struct {
+	Ipaddress string `json:"ipaddress"`
+	Status    string `json:"status"`
+	Lastseen  string `json:"lastseen"`
+	Lastscan  string `json:"lastscan"`
+}
+
+// LanHostService represents a port found opened on a device by the Bbox scan
+type LanHostService struct {
+	Protocol string  `json:"protocol"`
+	Port     flexInt `json:"port"`
+	State    string  `json:"state"`
+}
+
 // LanStatistics represents statistics information of the Bbox LAN
 type LanStatistics struct {
 	Lan struct {
@@ -105,13 +124,14 @@ type LanStatistics struct {
 	} `json:"lan"`
 }
 
+// LanIPInformations represents the IP configuration and the switch of the Bbox LAN
 type LanIPInformations struct {
 	Lan struct {
 		IP struct {
 			State      string        `json:"state"`
-			Mtu        int           `json:"mtu"`
+			Mtu        flexInt       `json:"mtu"`
 			Ipaddress  string        `json:"ipaddress"`
-			IP6Enable  int           `json:"ip6enable"`
+			IP6Enable  flexInt       `json:"ip6enable"`
 			IP6State   string        `json:"ip6state"`
 			IP6Address []interface{} `json:"ip6address"`
 			IP6Prefix  []interface{} `json:"ip6prefix"`
@@ -122,41 +142,66 @@ type LanIPInformations struct {
 			Aliases    string        `json:"aliases"`
 		} `json:"ip"`
 		Switch struct {
-			Ports []struct {
-				ID         int    `json:"id"`
-				State      string `json:"state"`
-				LinkMode   string `json:"link_mode"`
-				Blocked    int    `json:"blocked"`
-				Flickering int    `json:"flickering"`
-			} `json:"ports"`
+			Ports []LanSwitchPort `json:"ports"`
 		} `json:"switch"`
 	} `json:"lan"`
 }
 
-func (client *Client) getLanMetrics() (*LanMetrics, error) {
+// LanSwitchPort represents the state of an Ethernet port of the Bbox
+type LanSwitchPort struct {
+	ID         flexInt `json:"id"`
+	State      string  `json:"state"`
+	LinkMode   string  `json:"link_mode"` // Speed and duplex, e.g. "1000Full"
+	Blocked    flexInt `json:"blocked"`
+	Flickering flexInt `json:"flickering"`
+}
+
+// GetLanMetrics returns the IP configuration, switch, statistics and connected devices of the LAN
+// If only the IP configuration can't be retrieved, the other metrics are returned along
+// with the error.
+func (client *Client) GetLanMetrics(ctx context.Context) (*LanMetrics, error) {
 	var metrics LanMetrics
 
-	lanStats, err := client.getLanStatistics()
+	informations, informationsErr := client.getLanInformations(ctx)
+	metrics.IPInformations = informations
+
+	lanStats, err := client.getLanStatistics(ctx)
 	if err != nil {
 		return nil, err
 	}
 	metrics.Statistics = lanStats
 
-	devices, err := client.getLanDevices()
+	devices, err := client.getLanDevices(ctx)
 	if err != nil {
 		return nil, err
 	}
 	metrics.Devices = devices
 
-	return &metrics, nil
+	return &metrics, informationsErr
+}
+
+// GetLanHosts returns the devices connected to the LAN, active or not
+func (client *Client) GetLanHosts(ctx context.Context) ([]LanHost, error) {
+	devices, err := client.getLanDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	return devices[0].Hosts.List, nil
 }
 
 // returns ip configuration of the Bbox local Network.
 // See: https://api.bbox.fr/doc/apirouter/#api-LAN-GetLanIP
-func (client *Client) getLanInformations() ([]LanIPInformations, error) {
+func (client *Client) getLanInformations(ctx context.Context) ([]LanIPInformations, error) {
 	level.Info(client.logger).Log("msg", "Retrieve LAN IP informations from Bbox")
 	var informations []LanIPInformations
-	if err := client.apiRequest("/lan/ip", &informations); err != nil {
+	if err := client.apiRequest(ctx, "/lan/ip", &informations); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No LAN IP informations on Bbox")
+			return nil, nil
+		}
 		return nil, err
 	}
 	return informations, nil
@@ -164,10 +209,10 @@ func (client *Client) getLanInformations() ([]LanIPInformations, error) {
 
 // getLanDevices returns information on all devices connected to the Bbox.
 // See: https://api.bbox.fr/doc/apirouter/#api-LAN-GetHosts
-func (client *Client) getLanDevices() ([]LanDevice, error) {
+func (client *Client) getLanDevices(ctx context.Context) ([]LanDevice, error) {
 	level.Info(client.logger).Log("msg", "Retrieve LAN devices from Bbox")
 	var metrics []LanDevice
-	if err := client.apiRequest("/hosts", &metrics); err != nil {
+	if err := client.apiRequest(ctx, "/hosts", &metrics); err != nil {
 		return nil, err
 	}
 	return metrics, nil
@@ -175,10 +220,10 @@ func (client *Client) getLanDevices() ([]LanDevice, error) {
 
 // getLanStatistics returns statistics of the Bbox local Network.
 // See: https://api.bbox.fr/doc/apirouter/#api-LAN-GetLanStats
-func (client *Client) getLanStatistics() ([]LanStatistics, error) {
+func (client *Client) getLanStatistics(ctx context.Context) ([]LanStatistics, error) {
 	level.Info(client.logger).Log("msg", "Retrieve LAN IP statistics")
 	var metrics []LanStatistics
-	if err := client.apiRequest("/lan/stats", &metrics); err != nil {
+	if err := client.apiRequest(ctx, "/lan/stats", &metrics); err != nil {
 		return nil, err
 	}
 	return metrics, nil
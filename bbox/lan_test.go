@@ -0,0 +1,93 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+)
+
+func TestLanHostDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, host LanHost)
+	}{
+		{
+			name:  "ethernet host with empty wireless fields",
+			input: `{"id":1,"ethernet":{"speed":"1000"},"wireless":{"rssi0":"","rssi1":"","rssi2":"","mcs":"","rate":"","idle":""},"lease":0,"active":1}`,
+			check: func(t *testing.T, host LanHost) {
+				if host.Ethernet.Speed != 1000 || host.Wireless.Rssi0 != 0 || host.Wireless.Rate != 0 || host.Active != 1 {
+					t.Errorf("unexpected host: %+v", host)
+				}
+			},
+		},
+		{
+			name:  "wireless host with string numbers",
+			input: `{"wireless":{"band":"5","rssi0":"-61","rssi1":0,"rssi2":0,"mcs":9,"rate":"866","idle":1},"lease":"82311","ping":{"average":"8"}}`,
+			check: func(t *testing.T, host LanHost) {
+				if host.Wireless.Rssi0 != -61 || host.Wireless.Mcs != 9 || host.Wireless.Rate != 866 || host.Lease != 82311 || host.Ping.Average != 8 {
+					t.Errorf("unexpected host: %+v", host)
+				}
+			},
+		},
+		{
+			name:  "null fields",
+			input: `{"id":null,"lastseen":null,"ip6address":null,"wireless":{"rssi0":null,"rate":null},"ethernet":{"speed":null},"lease":null}`,
+			check: func(t *testing.T, host LanHost) {
+				if host.ID != 0 || host.Lastseen != 0 || host.IP6Address != nil || host.Wireless.Rate != 0 {
+					t.Errorf("unexpected host: %+v", host)
+				}
+			},
+		},
+		{
+			name:  "IPv6 addresses and scanned services",
+			input: `{"lastseen":"3","ip6address":[{"ipaddress":"2001:db8:1::21","status":"Valid"}],"scan":{"services":[{"protocol":"tcp","port":"80","state":"open"}]}}`,
+			check: func(t *testing.T, host LanHost) {
+				if host.Lastseen != 3 || len(host.IP6Address) != 1 || host.IP6Address[0].Ipaddress != "2001:db8:1::21" {
+					t.Errorf("unexpected host: %+v", host)
+				}
+				if len(host.Scan.Services) != 1 || host.Scan.Services[0].Port != 80 {
+					t.Errorf("unexpected services: %+v", host.Scan.Services)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var host LanHost
+			if err := json.Unmarshal([]byte(tt.input), &host); err != nil {
+				t.Fatalf("can't decode host: %s", err)
+			}
+			tt.check(t, host)
+		})
+	}
+}
+
+func TestLanHostFixture(t *testing.T) {
+	var devices []LanDevice
+	if err := json.Unmarshal(bboxtest.Fixture("hosts.json"), &devices); err != nil {
+		t.Fatalf("can't decode hosts: %s", err)
+	}
+	if len(devices) != 1 || len(devices[0].Hosts.List) != 3 {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+	host := devices[0].Hosts.List[2]
+	if host.Wireless.Rssi0 != -78 || host.Wireless.Rssi1 != -80 || host.Wireless.Mcs != 7 || host.Wireless.Idle != 120 {
+		t.Errorf("unexpected wireless fields: %+v", host.Wireless)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// deviceLogDateLayout is the format of the dates of the log of the Bbox
+const deviceLogDateLayout = "2006-01-02T15:04:05-0700"
+
+// DeviceLogEntry define an event of the internal log of the Bbox
+type DeviceLogEntry struct {
+	Date     string    `json:"date"`
+	Log      string    `json:"log"`
+	Severity string    `json:"severity"`
+	Param    string    `json:"param"`
+	Time     time.Time `json:"-"`
+}
+
+// DeviceLogEvent define a kind of event of the log of the Bbox
+type DeviceLogEvent struct {
+	Type     string
+	Severity string
+}
+
+// deviceLogKey identifies an entry of the log of the Bbox. The parsed time
+// isn't part of it, as its location differs between two parsings.
+type deviceLogKey struct {
+	Date     string
+	Log      string
+	Severity string
+	Param    string
+}
+
+func (entry DeviceLogEntry) key() deviceLogKey {
+	return deviceLogKey{Date: entry.Date, Log: entry.Log, Severity: entry.Severity, Param: entry.Param}
+}
+
+// deviceLog keeps the position in the log of the Bbox between scrapes, and
+// counts its events. Entries are deduplicated by date: the ones seen at the
+// most recent date are kept, as several events can be logged the same second.
+// Entries with an invalid date are kept while they are in the log, so they
+// are read once.
+type deviceLog struct {
+	sync.Mutex
+	last    time.Time
+	seen    map[deviceLogKey]bool
+	invalid map[deviceLogKey]bool
+	events  map[DeviceLogEvent]uint64
+}
+
+// ReadDeviceLog returns the entries of the log of the Bbox not read yet by
+// the client, oldest first after the ones with an invalid date, and counts
+// their events.
+func (client *Client) ReadDeviceLog(ctx context.Context) ([]DeviceLogEntry, error) {
+	entries, err := client.getDeviceLog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dated, invalid []DeviceLogEntry
+	for _, entry := range entries {
+		date, err := time.Parse(deviceLogDateLayout, entry.Date)
+		if err != nil {
+			invalid = append(invalid, entry)
+			continue
+		}
+		entry.Time = date
+		dated = append(dated, entry)
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].Time.Before(dated[j].Time)
+	})
+
+	client.deviceLog.Lock()
+	defer client.deviceLog.Unlock()
+	if client.deviceLog.events == nil {
+		client.deviceLog.events = make(map[DeviceLogEvent]uint64)
+	}
+	var news []DeviceLogEntry
+	invalidSeen := make(map[deviceLogKey]bool)
+	for _, entry := range invalid {
+		key := entry.key()
+		if !client.deviceLog.invalid[key] && !invalidSeen[key] {
+			level.Warn(client.logger).Log("msg", "Invalid date of log entry", "date", entry.Date)
+			news = append(news, entry)
+			client.deviceLog.events[DeviceLogEvent{Type: entry.Log, Severity: entry.Severity}]++
+		}
+		invalidSeen[key] = true
+	}
+	client.deviceLog.invalid = invalidSeen
+
+	last := client.deviceLog.last
+	seen := make(map[deviceLogKey]bool)
+	for key := range client.deviceLog.seen {
+		seen[key] = true
+	}
+	for _, entry := range dated {
+		key := entry.key()
+		if entry.Time.Before(client.deviceLog.last) {
+			continue
+		}
+		if entry.Time.Equal(client.deviceLog.last) && client.deviceLog.seen[key] {
+			continue
+		}
+		news = append(news, entry)
+		client.deviceLog.events[DeviceLogEvent{Type: entry.Log, Severity: entry.Severity}]++
+		if entry.Time.After(last) {
+			last = entry.Time
+			seen = make(map[deviceLogKey]bool)
+		}
+		seen[key] = true
+	}
+	client.deviceLog.last = last
+	client.deviceLog.seen = seen
+	return news, nil
+}
+
+// DeviceLogEvents returns the number of events read in the log of the Bbox
+func (client *Client) DeviceLogEvents() map[DeviceLogEvent]uint64 {
+	client.deviceLog.Lock()
+	defer client.deviceLog.Unlock()
+	events := make(map[DeviceLogEvent]uint64, len(client.deviceLog.events))
+	for event, count := range client.deviceLog.events {
+		events[event] = count
+	}
+	return events
+}
+
+// getDeviceLog returns the internal log of the Bbox, or nothing if the
+// firmware doesn't provide it.
+// See: https://api.bbox.fr/doc/apirouter/#api-Device-GetDeviceLog
+func (client *Client) getDeviceLog(ctx context.Context) ([]DeviceLogEntry, error) {
+	level.Info(client.logger).Log("msg", "Retrieve device log")
+	var deviceLog []struct {
+		Log []DeviceLogEntry `json:"log"`
+	}
+	if err := client.apiRequest(ctx, "/device/log", &deviceLog); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No device log on Bbox")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(deviceLog) == 0 {
+		return nil, nil
+	}
+	return deviceLog[0].Log, nil
+}
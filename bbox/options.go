@@ -0,0 +1,79 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// Option configures the Client
+type Option func(*Client)
+
+// WithTimeout sets the timeout of each request to the Bbox API
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *Client) {
+		if timeout > 0 {
+			client.timeout = timeout
+		}
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the Bbox
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(client *Client) {
+		client.tlsConfig = tlsConfig
+	}
+}
+
+// WithTransport sets the transport used to send the requests.
+// It takes precedence over WithTLSConfig.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(client *Client) {
+		client.transport = transport
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send the requests.
+// It takes precedence over the other options.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy sets how the GET requests are retried on transient errors.
+// A MaxAttempts of 1 disables the retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(client *Client) {
+		if policy.MaxAttempts > 0 {
+			client.retry = policy
+		}
+	}
+}
+
+// WithCircuitBreaker sets the number of consecutive failures opening the
+// circuit breaker, and the cool-down during which no request is sent.
+func WithCircuitBreaker(failureThreshold int, cooldown time.Duration) Option {
+	return func(client *Client) {
+		if failureThreshold > 0 {
+			client.breaker.failureThreshold = failureThreshold
+		}
+		if cooldown > 0 {
+			client.breaker.cooldown = cooldown
+		}
+	}
+}
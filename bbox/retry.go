@@ -0,0 +1,203 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy defines how the GET requests to the Bbox API are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled on each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used when no retry policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// backoff returns the wait before the given retry, with a random jitter
+// between the half and the full exponential backoff.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// isRetryable checks if the request failed because of a transient error:
+// a connection error or a server error.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode >= http.StatusInternalServerError
+	}
+	var decodeError *DecodeError
+	return !errors.As(err, &decodeError) && !errors.Is(err, ErrCircuitOpen)
+}
+
+// CircuitState is the state of the circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets the requests go to the Bbox
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects the requests until the end of the cool-down
+	CircuitOpen
+	// CircuitHalfOpen lets a single request go to the Bbox, to check if it recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = time.Minute
+)
+
+// circuitBreaker stops sending requests to an unreachable Bbox during the
+// cool-down, after failureThreshold consecutive failures.
+type circuitBreaker struct {
+	sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	failures         int
+	state            CircuitState
+	openedAt         time.Time
+}
+
+// allow checks if a request can be sent.
+func (cb *circuitBreaker) allow() error {
+	cb.Lock()
+	defer cb.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return ErrCircuitOpen
+		}
+		cb.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// A request is already checking if the Bbox recovered
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record updates the state from the result of a request.
+func (cb *circuitBreaker) record(failed bool) {
+	cb.Lock()
+	defer cb.Unlock()
+	if !failed {
+		cb.failures = 0
+		cb.state = CircuitClosed
+		return
+	}
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.failureThreshold {
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// cancel releases the check of the half-open state, when its request was cancelled.
+func (cb *circuitBreaker) cancel() {
+	cb.Lock()
+	defer cb.Unlock()
+	if cb.state == CircuitHalfOpen {
+		cb.state = CircuitOpen
+	}
+}
+
+func (cb *circuitBreaker) getState() CircuitState {
+	cb.Lock()
+	defer cb.Unlock()
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.cooldown {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// CircuitBreakerState returns the state of the circuit breaker of the client
+func (client *Client) CircuitBreakerState() CircuitState {
+	return client.breaker.getState()
+}
+
+// send sends the request to the Bbox, through the circuit breaker.
+func (client *Client) send(req *http.Request) (*http.Response, error) {
+	if err := client.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			// A cancelled scrape doesn't tell anything about the Bbox
+			client.breaker.cancel()
+		} else {
+			client.breaker.record(true)
+		}
+		return nil, err
+	}
+	client.breaker.record(resp.StatusCode >= http.StatusInternalServerError)
+	return resp, nil
+}
+
+// wait sleeps before the retry, unless the context is done.
+func wait(ctx context.Context, backoff time.Duration) error {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+)
+
+func newTestClient(t *testing.T, server *bboxtest.Server, opts ...Option) *Client {
+	t.Helper()
+	opts = append([]Option{WithHTTPClient(server.Client())}, opts...)
+	client, err := NewClient(server.URL, bboxtest.DefaultPassword, log.NewNopLogger(), opts...)
+	if err != nil {
+		t.Fatalf("can't create client: %s", err)
+	}
+	return client
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     2 * time.Millisecond,
+}
+
+func TestRetryServerError(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	client := newTestClient(t, server, WithRetryPolicy(testRetryPolicy))
+
+	server.SetFault("/device", bboxtest.Fault{StatusCode: http.StatusInternalServerError})
+	_, err := client.GetDeviceInformations(context.Background())
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected an API error 500, got %v", err)
+	}
+	if requests := server.Requests("/device"); requests != 3 {
+		t.Errorf("expected 3 attempts, got %d", requests)
+	}
+
+	server.ClearFaults()
+	if _, err := client.GetDeviceInformations(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    bboxtest.Fault
+		requests int
+	}{
+		{
+			name:     "decode error",
+			fault:    bboxtest.Fault{Body: `[{"device":`},
+			requests: 1,
+		},
+		{
+			// The session is renewed once, without retrying
+			name:     "unauthorized",
+			fault:    bboxtest.Fault{StatusCode: http.StatusUnauthorized},
+			requests: 2,
+		},
+		{
+			name:     "not found",
+			fault:    bboxtest.Fault{StatusCode: http.StatusNotFound},
+			requests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := bboxtest.NewServer()
+			defer server.Close()
+			client := newTestClient(t, server, WithRetryPolicy(testRetryPolicy))
+
+			server.SetFault("/device", tt.fault)
+			if _, err := client.GetDeviceInformations(context.Background()); err == nil {
+				t.Fatalf("expected an error")
+			}
+			if requests := server.Requests("/device"); requests != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	cooldown := 50 * time.Millisecond
+	client := newTestClient(t, server,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithCircuitBreaker(2, cooldown))
+	ctx := context.Background()
+
+	server.SetFault("/device", bboxtest.Fault{StatusCode: http.StatusInternalServerError})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetDeviceInformations(ctx); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected an API error, got %v", err)
+		}
+	}
+	if state := client.CircuitBreakerState(); state != CircuitOpen {
+		t.Fatalf("expected an open circuit breaker, got %s", state)
+	}
+
+	// Nothing is sent while the circuit breaker is open, not even a login
+	attempts := client.LoginAttempts()
+	if _, err := client.GetDeviceInformations(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if err := client.Authenticate(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if requests := server.Requests("/device"); requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if client.LoginAttempts() != attempts || client.LoginFailures() != 0 {
+		t.Errorf("login counted while the circuit breaker is open: %d attempts, %d failures",
+			client.LoginAttempts(), client.LoginFailures())
+	}
+
+	// A failure while half-open opens it again
+	time.Sleep(cooldown)
+	if state := client.CircuitBreakerState(); state != CircuitHalfOpen {
+		t.Fatalf("expected a half-open circuit breaker, got %s", state)
+	}
+	server.ClearFaults()
+	server.SetFault("/login", bboxtest.Fault{StatusCode: http.StatusInternalServerError})
+	if err := client.Authenticate(ctx); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected an API error, got %v", err)
+	}
+	if state := client.CircuitBreakerState(); state != CircuitOpen {
+		t.Fatalf("expected an open circuit breaker, got %s", state)
+	}
+
+	// A success while half-open closes it
+	time.Sleep(cooldown)
+	server.ClearFaults()
+	if _, err := client.GetDeviceInformations(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if state := client.CircuitBreakerState(); state != CircuitClosed {
+		t.Errorf("expected a closed circuit breaker, got %s", state)
+	}
+}
@@ -14,7 +14,11 @@
 
 package bbox
 
-import "github.com/go-kit/kit/log/level"
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+)
 
 type ServicesMetrics struct {
 	Informations []ServicesInformations
@@ -24,89 +28,90 @@ type ServicesInformations struct {
 	Services struct {
 		Now      string `json:"now"`
 		Firewall struct {
-			Status  int `json:"status"`
-			Enable  int `json:"enable"`
-			Nbrules int `json:"nbrules"`
+			Status  flexInt `json:"status"`
+			Enable  flexInt `json:"enable"`
+			Nbrules flexInt `json:"nbrules"`
 		} `json:"firewall"`
 		Dyndns struct {
-			State   int `json:"state"`
-			Enable  int `json:"enable"`
-			Nbrules int `json:"nbrules"`
+			State   flexInt `json:"state"`
+			Enable  flexInt `json:"enable"`
+			Nbrules flexInt `json:"nbrules"`
 		} `json:"dyndns"`
 		Dhcp struct {
-			Status  int `json:"status"`
-			Enable  int `json:"enable"`
-			Nbrules int `json:"nbrules"`
+			Status  flexInt `json:"status"`
+			Enable  flexInt `json:"enable"`
+			Nbrules flexInt `json:"nbrules"`
 		} `json:"dhcp"`
 		Nat struct {
-			Status  int `json:"status"`
-			Enable  int `json:"enable"`
-			Nbrules int `json:"nbrules"`
+			Status  flexInt `json:"status"`
+			Enable  flexInt `json:"enable"`
+			Nbrules flexInt `json:"nbrules"`
 		} `json:"nat"`
 		Gamermode struct {
-			Status int `json:"status"`
-			Enable int `json:"enable"`
+			Status flexInt `json:"status"`
+			Enable flexInt `json:"enable"`
 		} `json:"gamermode"`
 		Upnp struct {
 			Igd struct {
-				Status  int `json:"status"`
-				Enable  int `json:"enable"`
-				Nbrules int `json:"nbrules"`
+				Status  flexInt `json:"status"`
+				Enable  flexInt `json:"enable"`
+				Nbrules flexInt `json:"nbrules"`
 			} `json:"igd"`
 		} `json:"upnp"`
 		Remote struct {
 			Proxywol struct {
-				Status int    `json:"status"`
-				Enable int    `json:"enable"`
-				IP     string `json:"ip"`
+				Status flexInt `json:"status"`
+				Enable flexInt `json:"enable"`
+				IP     string  `json:"ip"`
 			} `json:"proxywol"`
 			Admin struct {
-				Status     int    `json:"status"`
-				Enable     int    `json:"enable"`
-				Port       int    `json:"port"`
-				IP         string `json:"ip"`
-				Duration   string `json:"duration"`
-				Activable  int    `json:"activable"`
-				IP6Address string `json:"ip6address"`
+				Status     flexInt `json:"status"`
+				Enable     flexInt `json:"enable"`
+				Port       flexInt `json:"port"`
+				IP         string  `json:"ip"`
+				Duration   string  `json:"duration"`
+				Activable  flexInt `json:"activable"`
+				IP6Address string  `json:"ip6address"`
 			} `json:"admin"`
 		} `json:"remote"`
 		Parentalcontrol struct {
-			Enable int `json:"enable"`
+			Enable flexInt `json:"enable"`
 		} `json:"parentalcontrol"`
 		Wifischeduler struct {
-			Enable int `json:"enable"`
+			Enable flexInt `json:"enable"`
 		} `json:"wifischeduler"`
 		Voipscheduler struct {
-			Enable int `json:"enable"`
+			Enable flexInt `json:"enable"`
 		} `json:"voipscheduler"`
 		Notification struct {
-			Enable int `json:"enable"`
+			Enable flexInt `json:"enable"`
 		} `json:"notification"`
 		Hotspot struct {
-			Status int `json:"status"`
-			Enable int `json:"enable"`
+			Status flexInt `json:"status"`
+			Enable flexInt `json:"enable"`
 		} `json:"hotspot"`
 		Usb struct {
 			Samba struct {
-				Status int `json:"status"`
-				Enable int `json:"enable"`
+				Status flexInt `json:"status"`
+				Enable flexInt `json:"enable"`
 			} `json:"samba"`
 			Printer struct {
-				Status int `json:"status"`
-				Enable int `json:"enable"`
+				Status flexInt `json:"status"`
+				Enable flexInt `json:"enable"`
 			} `json:"printer"`
 			Dlna struct {
-				Status int `json:"status"`
-				Enable int `json:"enable"`
+				Status flexInt `json:"status"`
+				Enable flexInt `json:"enable"`
 			} `json:"dlna"`
 		} `json:"usb"`
 	} `json:"services"`
 }
 
-func (client *Client) getServicesMetrics() (*ServicesMetrics, error) {
+// GetServicesMetrics returns the state of the Bbox services
+func (client *Client) GetServicesMetrics(ctx context.Context) (*ServicesMetrics, error) {
 	var metrics ServicesMetrics
 
-	informations, err := client.getServicesInformations()
+	informations, err := client.getServicesInformations(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -117,10 +122,10 @@ func (client *Client) getServicesMetrics() (*ServicesMetrics, error) {
 
 // getServicesInformations returns Services information
 // See: https://api.bbox.fr/doc/apirouter/#api-Services-GetServices
-func (client *Client) getServicesInformations() ([]ServicesInformations, error) {
+func (client *Client) getServicesInformations(ctx context.Context) ([]ServicesInformations, error) {
 	level.Info(client.logger).Log("msg", "Retrieve Services informations from Bbox")
 	var informations []ServicesInformations
-	if err := client.apiRequest("/services", &informations); err != nil {
+	if err := client.apiRequest(ctx, "/services", &informations); err != nil {
 		return nil, err
 	}
 	return informations, nil
@@ -0,0 +1,74 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// session holds the cookies of the Bbox API, shared between requests.
+// id is incremented on each login, so concurrent requests which find an
+// expired session authenticate only once.
+type session struct {
+	sync.Mutex
+	cookies       []*http.Cookie
+	id            int
+	loginAttempts uint64
+	loginFailures uint64
+}
+
+// Connect authenticates on the Bbox API, unless a session is already opened
+func (client *Client) Connect(ctx context.Context) error {
+	_, _, err := client.getSession(ctx)
+	return err
+}
+
+// LoginAttempts returns the number of authentications on the Bbox API
+func (client *Client) LoginAttempts() uint64 {
+	return atomic.LoadUint64(&client.session.loginAttempts)
+}
+
+// LoginFailures returns the number of failed authentications on the Bbox API
+func (client *Client) LoginFailures() uint64 {
+	return atomic.LoadUint64(&client.session.loginFailures)
+}
+
+// getSession returns the cookies of the current session, authenticating if none.
+func (client *Client) getSession(ctx context.Context) ([]*http.Cookie, int, error) {
+	client.session.Lock()
+	defer client.session.Unlock()
+	if client.session.cookies == nil {
+		if err := client.login(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	return client.session.cookies, client.session.id, nil
+}
+
+// renewSession authenticates again, unless another request already did it
+// since the expired session was used.
+func (client *Client) renewSession(ctx context.Context, expired int) ([]*http.Cookie, error) {
+	client.session.Lock()
+	defer client.session.Unlock()
+	if client.session.id == expired || client.session.cookies == nil {
+		if err := client.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return client.session.cookies, nil
+}
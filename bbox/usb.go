@@ -0,0 +1,118 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// UsbMetrics define the devices plugged on the USB ports of the Bbox
+type UsbMetrics struct {
+	Printers []UsbPrinter
+	Storages []UsbStorage
+}
+
+// UsbPrinter represents a printer shared by the Bbox
+type UsbPrinter struct {
+	ID           flexInt `json:"id"`
+	Manufacturer string  `json:"manufacturer"`
+	Product      string  `json:"product"`
+	State        string  `json:"state"`
+}
+
+// UsbStorage represents a storage device shared by the Bbox
+type UsbStorage struct {
+	ID           flexInt        `json:"id"`
+	Manufacturer string         `json:"manufacturer"`
+	Product      string         `json:"product"`
+	State        string         `json:"state"`
+	Partitions   []UsbPartition `json:"partitions"`
+}
+
+// UsbPartition represents a partition of a storage device, sizes in bytes.
+// Free is unset when the firmware doesn't report it.
+type UsbPartition struct {
+	ID     flexInt       `json:"id"`
+	Label  string        `json:"label"`
+	FsType string        `json:"fstype"`
+	State  string        `json:"state"`
+	Total  flexFloat     `json:"total"`
+	Used   flexFloat     `json:"used"`
+	Free   optionalFloat `json:"free"`
+}
+
+// GetUsbMetrics returns the printers and storage devices plugged on the Bbox
+func (client *Client) GetUsbMetrics(ctx context.Context) (*UsbMetrics, error) {
+	var metrics UsbMetrics
+
+	printers, err := client.getUsbPrinters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Printers = printers
+
+	storages, err := client.getUsbStorages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Storages = storages
+
+	return &metrics, nil
+}
+
+// getUsbPrinters returns the printers plugged on the Bbox
+// See: https://api.bbox.fr/doc/apirouter/#api-USB-GetUSB
+func (client *Client) getUsbPrinters(ctx context.Context) ([]UsbPrinter, error) {
+	level.Info(client.logger).Log("msg", "Retrieve USB devices")
+	var usb []struct {
+		Usb struct {
+			Printer []UsbPrinter `json:"printer"`
+		} `json:"usb"`
+	}
+	if err := client.apiRequest(ctx, "/usb", &usb); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No USB on Bbox")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(usb) == 0 {
+		return nil, nil
+	}
+	return usb[0].Usb.Printer, nil
+}
+
+// getUsbStorages returns the storage devices plugged on the Bbox
+// See: https://api.bbox.fr/doc/apirouter/#api-USB-GetUSBStorage
+func (client *Client) getUsbStorages(ctx context.Context) ([]UsbStorage, error) {
+	level.Info(client.logger).Log("msg", "Retrieve USB storage")
+	var usb []struct {
+		Storage []UsbStorage `json:"storage"`
+	}
+	if err := client.apiRequest(ctx, "/usb/storage", &usb); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No USB storage on Bbox")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(usb) == 0 {
+		return nil, nil
+	}
+	return usb[0].Storage, nil
+}
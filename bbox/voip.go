@@ -0,0 +1,104 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+type VoipMetrics struct {
+	Lines    []VoipLine
+	CallLogs map[int][]VoipCall
+}
+
+// VoipLine represents the state of a phone line of the Bbox
+type VoipLine struct {
+	ID            flexInt `json:"id"`
+	Status        string  `json:"status"`
+	CallState     string  `json:"callstate"`
+	URI           string  `json:"uri"`
+	BlockState    flexInt `json:"blockstate"`
+	AnonCallState flexInt `json:"anoncallstate"`
+	MWI           flexInt `json:"mwi"`
+	MessageCount  flexInt `json:"message_count"`
+	NotAnswered   flexInt `json:"notanswered"`
+}
+
+// VoipCall represents a call of the call log.
+// The phone number is not decoded.
+type VoipCall struct {
+	ID       flexInt `json:"id"`
+	Date     flexInt `json:"date"`
+	Type     string  `json:"type"` // "in" or "out"
+	Answered flexInt `json:"answered"`
+	Duration flexInt `json:"duree"`
+}
+
+// GetVoipMetrics returns the phone lines and their call logs
+func (client *Client) GetVoipMetrics(ctx context.Context) (*VoipMetrics, error) {
+	metrics := VoipMetrics{
+		CallLogs: make(map[int][]VoipCall),
+	}
+
+	lines, err := client.getVoipLines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Lines = lines
+
+	for _, line := range lines {
+		calls, err := client.getVoipCallLog(ctx, int(line.ID))
+		if err != nil {
+			return nil, err
+		}
+		metrics.CallLogs[int(line.ID)] = calls
+	}
+	return &metrics, nil
+}
+
+// getVoipLines returns the state of the phone lines
+// See: https://api.bbox.fr/doc/apirouter/#api-VOIP-GetVOIP
+func (client *Client) getVoipLines(ctx context.Context) ([]VoipLine, error) {
+	level.Info(client.logger).Log("msg", "Retrieve VoIP lines from Bbox")
+	var response []struct {
+		Voip []VoipLine `json:"voip"`
+	}
+	if err := client.apiRequest(ctx, "/voip", &response); err != nil {
+		return nil, err
+	}
+	if len(response) == 0 {
+		return nil, nil
+	}
+	return response[0].Voip, nil
+}
+
+// getVoipCallLog returns the calls of a phone line
+// See: https://api.bbox.fr/doc/apirouter/#api-VOIP-GetVOIPFullCallLog
+func (client *Client) getVoipCallLog(ctx context.Context, line int) ([]VoipCall, error) {
+	level.Info(client.logger).Log("msg", "Retrieve VoIP call log from Bbox", "line", line)
+	var response []struct {
+		CallLog []VoipCall `json:"calllog"`
+	}
+	if err := client.apiRequest(ctx, fmt.Sprintf("/voip/fullcalllog/%d", line), &response); err != nil {
+		return nil, err
+	}
+	if len(response) == 0 {
+		return nil, nil
+	}
+	return response[0].CallLog, nil
+}
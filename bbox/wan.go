@@ -15,13 +15,15 @@
 package bbox
 
 import (
+	"context"
+	"sync"
+
 	"github.com/go-kit/kit/log/level"
 )
 
 type WanMetrics struct {
 	IPInformations        []WanIPInformations  `json:"ip_informations"`
 	IPStatistics          []WanIPStatistics    `json:"ip_statistics"`
-	FtthStatistics        *FtthStatistics      `json:"ftth_statistics"`
 	DiagnosticsStatistics []WanDiagsStatistics `json:"diagnostics"`
 }
 
@@ -52,40 +54,27 @@ type WanIPStatistics struct {
 	} `json:"wan"`
 }
 
-type FtthStatistics []struct {
-	Ftth Ftth `json:"ftth"`
-}
-
-type Ftth struct {
-	Wan struct {
-		Ftth struct {
-			Mode  string `json:"mode"`
-			State string `json:"state"`
-		} `json:"ftth"`
-	} `json:"wan"`
-}
-
 type WanIPInformations struct {
 	Wan struct {
 		Internet struct {
-			State int `json:"state"`
+			State flexInt `json:"state"`
 		} `json:"internet"`
 		Interface struct {
-			ID      int `json:"id"`
-			Default int `json:"default"`
-			State   int `json:"state"`
+			ID      flexInt `json:"id"`
+			Default flexInt `json:"default"`
+			State   flexInt `json:"state"`
 		} `json:"interface"`
 		IP struct {
-			Address    string        `json:"address"`
-			State      string        `json:"state"`
-			Gateway    string        `json:"gateway"`
-			Dnsservers string        `json:"dnsservers"`
-			Subnet     string        `json:"subnet"`
-			IP6State   string        `json:"ip6state"`
-			IP6Address []interface{} `json:"ip6address"`
-			IP6Prefix  []interface{} `json:"ip6prefix"`
-			Mac        string        `json:"mac"`
-			Mtu        int           `json:"mtu"`
+			Address    string          `json:"address"`
+			State      string          `json:"state"`
+			Gateway    string          `json:"gateway"`
+			Dnsservers string          `json:"dnsservers"`
+			Subnet     string          `json:"subnet"`
+			IP6State   string          `json:"ip6state"`
+			IP6Address []WanIP6Address `json:"ip6address"`
+			IP6Prefix  []WanIP6Prefix  `json:"ip6prefix"`
+			Mac        string          `json:"mac"`
+			Mtu        flexInt         `json:"mtu"`
 		} `json:"ip"`
 		Link struct {
 			State string `json:"state"`
@@ -94,6 +83,30 @@ type WanIPInformations struct {
 	} `json:"wan"`
 }
 
+// WanIP6Address represents an IPv6 address of the WAN
+type WanIP6Address struct {
+	IPAddress string `json:"ipaddress"`
+	Status    string `json:"status"`
+	Valid     string `json:"valid"`
+	Preferred string `json:"preferred"`
+}
+
+// WanIP6Prefix represents an IPv6 prefix delegated to the Bbox
+type WanIP6Prefix struct {
+	Prefix    string `json:"prefix"`
+	Status    string `json:"status"`
+	Valid     string `json:"valid"`
+	Preferred string `json:"preferred"`
+}
+
+// wanAddress keeps the public IPv4 address of the WAN between scrapes, and
+// counts its changes.
+type wanAddress struct {
+	sync.Mutex
+	address string
+	changes uint64
+}
+
 type WanDiagsStatistics struct {
 	Diags struct {
 		DNS []struct {
@@ -129,28 +142,26 @@ type WanDiagsStatistics struct {
 	} `json:"diags"`
 }
 
-func (client *Client) getWanMetrics() (*WanMetrics, error) {
+// GetWanMetrics returns the IP informations, statistics and diagnostics of the WAN
+func (client *Client) GetWanMetrics(ctx context.Context) (*WanMetrics, error) {
 	var metrics WanMetrics
 
-	wanIPInformations, err := client.getWanInformations()
+	wanIPInformations, err := client.getWanInformations(ctx)
 	if err != nil {
 		return nil, err
 	}
 	metrics.IPInformations = wanIPInformations
-
-	wanIPStats, err := client.getWanStatistics()
-	if err != nil {
-		return nil, err
+	if len(wanIPInformations) > 0 {
+		client.updateWanAddress(wanIPInformations[0].Wan.IP.Address)
 	}
-	metrics.IPStatistics = wanIPStats
 
-	ftthStats, err := client.getWanFtthStatistics()
+	wanIPStats, err := client.getWanStatistics(ctx)
 	if err != nil {
 		return nil, err
 	}
-	metrics.FtthStatistics = ftthStats
+	metrics.IPStatistics = wanIPStats
 
-	diagsStats, err := client.getWANDiagnostics()
+	diagsStats, err := client.getWANDiagnostics(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -159,12 +170,35 @@ func (client *Client) getWanMetrics() (*WanMetrics, error) {
 	return &metrics, nil
 }
 
+// WanIPChanges returns the number of changes of the public IPv4 address of
+// the WAN seen by the client
+func (client *Client) WanIPChanges() uint64 {
+	client.wanAddress.Lock()
+	defer client.wanAddress.Unlock()
+	return client.wanAddress.changes
+}
+
+// updateWanAddress counts a change of the public IPv4 address. The address
+// is empty while the WAN is down, which is not a change.
+func (client *Client) updateWanAddress(address string) {
+	if address == "" {
+		return
+	}
+	client.wanAddress.Lock()
+	defer client.wanAddress.Unlock()
+	if client.wanAddress.address != "" && client.wanAddress.address != address {
+		level.Info(client.logger).Log("msg", "Public IP address changed", "previous", client.wanAddress.address, "address", address)
+		client.wanAddress.changes++
+	}
+	client.wanAddress.address = address
+}
+
 // getWanInformations returns WAN IP Information
 // See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetWANIP
-func (client *Client) getWanInformations() ([]WanIPInformations, error) {
+func (client *Client) getWanInformations(ctx context.Context) ([]WanIPInformations, error) {
 	level.Info(client.logger).Log("msg", "Retrieve WAN IP informations from Bbox")
 	var informations []WanIPInformations
-	if err := client.apiRequest("/wan/ip", &informations); err != nil {
+	if err := client.apiRequest(ctx, "/wan/ip", &informations); err != nil {
 		return nil, err
 	}
 	return informations, nil
@@ -172,32 +206,21 @@ func (client *Client) getWanInformations() ([]WanIPInformations, error) {
 
 // getWanStatistics returns WAN IP statistics
 // See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetWANIPStats
-func (client *Client) getWanStatistics() ([]WanIPStatistics, error) {
+func (client *Client) getWanStatistics(ctx context.Context) ([]WanIPStatistics, error) {
 	level.Info(client.logger).Log("msg", "Retrieve WAN metrics from Bbox")
 	var metrics []WanIPStatistics
-	if err := client.apiRequest("/wan/ip/stats", &metrics); err != nil {
+	if err := client.apiRequest(ctx, "/wan/ip/stats", &metrics); err != nil {
 		return nil, err
 	}
 	return metrics, nil
 }
 
-// getWanFtthStatistics returns information about FTTH
-// See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetFTTHStats
-func (client *Client) getWanFtthStatistics() (*FtthStatistics, error) {
-	level.Info(client.logger).Log("msg", "Retrieve WAN metrics from Bbox")
-	var metrics FtthStatistics
-	if err := client.apiRequest("/wan/ftth/stats", &metrics); err != nil {
-		return nil, err
-	}
-	return &metrics, nil
-}
-
 // getWANDiagnostics return results of the tests to retrieve the real state of the Internet connectivity
 // https://api.bbox.fr/doc/apirouter/index.html#api-WAN-GetWANDiags
-func (client *Client) getWANDiagnostics() ([]WanDiagsStatistics, error) {
+func (client *Client) getWANDiagnostics(ctx context.Context) ([]WanDiagsStatistics, error) {
 	level.Info(client.logger).Log("msg", "Retrieve WAN diagnostics from Bbox")
 	var metrics []WanDiagsStatistics
-	if err := client.apiRequest("/wan/diags", &metrics); err != nil {
+	if err := client.apiRequest(ctx, "/wan/diags", &metrics); err != nil {
 		return nil, err
 	}
 	return metrics, nil
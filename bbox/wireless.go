@@ -15,16 +15,54 @@
 package bbox
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/go-kit/kit/log/level"
 )
 
 type WirelessMetrics struct {
+	Informations            []WirelessInformations
 	Wireless5GhzStatistics  []WirelessStatistics
 	Wireless24GhzStatistics []WirelessStatistics
 }
 
+// WirelessInformations represents the configuration of the Bbox WIFI.
+// Radios and SSID are indexed by band: "24" or "5".
+type WirelessInformations struct {
+	Wireless struct {
+		Status string                   `json:"status"`
+		Radio  map[string]WirelessRadio `json:"radio"`
+		SSID   map[string]WirelessSSID  `json:"ssid"`
+	} `json:"wireless"`
+}
+
+// WirelessRadio represents the configuration of a WIFI radio
+type WirelessRadio struct {
+	Enable         flexInt `json:"enable"`
+	State          flexInt `json:"state"`
+	Standard       string  `json:"standard"`
+	Channel        flexInt `json:"channel"`
+	CurrentChannel flexInt `json:"current_channel"`
+	DFS            flexInt `json:"dfs"`
+	HTBW           flexInt `json:"htbw"`
+	TxPower        flexInt `json:"txpower"`
+}
+
+// WirelessSSID represents the configuration of a WIFI network.
+// The passphrase is not decoded.
+type WirelessSSID struct {
+	ID       string  `json:"id"`
+	Enable   flexInt `json:"enable"`
+	Hidden   flexInt `json:"hidden"`
+	BSSID    string  `json:"bssid"`
+	Security struct {
+		Protocol   string `json:"protocol"`
+		Encryption string `json:"encryption"`
+	} `json:"security"`
+}
+
 // WirelessStatistics represents statistics information of the Bbox WIFI
 type WirelessStatistics struct {
 	Wireless struct {
@@ -48,29 +86,54 @@ type WirelessStatistics struct {
 	} `json:"wireless"`
 }
 
-func (client *Client) getWirelessMetrics() (*WirelessMetrics, error) {
+// GetWirelessMetrics returns the configuration and the statistics of the WIFI.
+// A band which is disabled or unknown on the firmware of the Bbox has no statistics.
+// If only the configuration can't be retrieved, the statistics are returned along
+// with the error.
+func (client *Client) GetWirelessMetrics(ctx context.Context) (*WirelessMetrics, error) {
 	var metrics WirelessMetrics
 
-	// wifi5Ghz, err := client.getWirelessStatistics("5")
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// metrics.Wireless5GhzStatistics = wifi5Ghz
+	informations, informationsErr := client.getWirelessInformations(ctx)
+	metrics.Informations = informations
 
-	wifi24Ghz, err := client.getWirelessStatistics("24")
+	wifi5Ghz, err := client.getWirelessStatistics(ctx, "5")
+	if err != nil {
+		return nil, err
+	}
+	metrics.Wireless5GhzStatistics = wifi5Ghz
+
+	wifi24Ghz, err := client.getWirelessStatistics(ctx, "24")
 	if err != nil {
 		return nil, err
 	}
 	metrics.Wireless24GhzStatistics = wifi24Ghz
 
-	return &metrics, nil
+	return &metrics, informationsErr
+}
+
+func (client *Client) getWirelessInformations(ctx context.Context) ([]WirelessInformations, error) {
+	level.Info(client.logger).Log("msg", "Retrieve WIFI informations from Bbox")
+
+	var informations []WirelessInformations
+	if err := client.apiRequest(ctx, "/wireless", &informations); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No WIFI informations on Bbox")
+			return nil, nil
+		}
+		return nil, err
+	}
+	return informations, nil
 }
 
-func (client *Client) getWirelessStatistics(which string) ([]WirelessStatistics, error) {
-	level.Info(client.logger).Log("msg", "Retrieve WIFI %sGhz metrics from Bbox", which)
+func (client *Client) getWirelessStatistics(ctx context.Context, which string) ([]WirelessStatistics, error) {
+	level.Info(client.logger).Log("msg", "Retrieve WIFI metrics from Bbox", "frequency", which)
 
 	var metrics []WirelessStatistics
-	if err := client.apiRequest(fmt.Sprintf("/wireless/%s/stats", which), &metrics); err != nil {
+	if err := client.apiRequest(ctx, fmt.Sprintf("/wireless/%s/stats", which), &metrics); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			level.Debug(client.logger).Log("msg", "No WIFI band on Bbox", "frequency", which)
+			return nil, nil
+		}
 		return nil, err
 	}
 	return metrics, nil
@@ -0,0 +1,111 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bbox
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+type XdslMetrics struct {
+	Informations []XdslInformations `json:"informations"`
+	Statistics   []XdslStatistics   `json:"statistics"`
+}
+
+// XdslInformations represents the state of the xDSL line
+type XdslInformations struct {
+	WAN struct {
+		Xdsl struct {
+			State        string            `json:"state"`
+			Modulation   string            `json:"modulation"`
+			Profile      string            `json:"profile"`
+			Showtime     flexInt           `json:"showtime"`
+			SyncCount    flexInt           `json:"sync_count"`
+			AtucProvider string            `json:"atuc_provider"`
+			Up           XdslLineDirection `json:"up"`
+			Down         XdslLineDirection `json:"down"`
+		} `json:"xdsl"`
+	} `json:"wan"`
+}
+
+// XdslLineDirection represents the synchronization of a direction of the xDSL line
+type XdslLineDirection struct {
+	Bitrates        flexInt   `json:"bitrates"`    // kbps
+	MaxBitrates     flexInt   `json:"maxbitrates"` // kbps
+	Noise           flexFloat `json:"noise"`       // SNR margin in dB
+	Attenuation     flexFloat `json:"attenuation"` // dB
+	Power           flexFloat `json:"power"`
+	Phyr            flexInt   `json:"phyr"`
+	Ginp            flexInt   `json:"ginp"`
+	InterleaveDelay flexInt   `json:"interleave_delay"`
+}
+
+// XdslStatistics represents the error counters of the xDSL line
+type XdslStatistics struct {
+	WAN struct {
+		Xdsl struct {
+			Stats struct {
+				LocalCRC  flexInt `json:"local_crc"`
+				LocalFEC  flexInt `json:"local_fec"`
+				LocalHEC  flexInt `json:"local_hec"`
+				RemoteCRC flexInt `json:"remote_crc"`
+				RemoteFEC flexInt `json:"remote_fec"`
+				RemoteHEC flexInt `json:"remote_hec"`
+			} `json:"stats"`
+		} `json:"xdsl"`
+	} `json:"wan"`
+}
+
+// GetXdslMetrics returns the state and the statistics of the xDSL line
+func (client *Client) GetXdslMetrics(ctx context.Context) (*XdslMetrics, error) {
+	var metrics XdslMetrics
+
+	informations, err := client.getXdslInformations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Informations = informations
+
+	statistics, err := client.getXdslStatistics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Statistics = statistics
+
+	return &metrics, nil
+}
+
+// getXdslInformations returns the synchronization of the xDSL line
+// See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetWANXDSL
+func (client *Client) getXdslInformations(ctx context.Context) ([]XdslInformations, error) {
+	level.Info(client.logger).Log("msg", "Retrieve xDSL informations from Bbox")
+	var informations []XdslInformations
+	if err := client.apiRequest(ctx, "/wan/xdsl", &informations); err != nil {
+		return nil, err
+	}
+	return informations, nil
+}
+
+// getXdslStatistics returns the error counters of the xDSL line
+// See: https://api.bbox.fr/doc/apirouter/#api-WAN-GetWANXDSLStats
+func (client *Client) getXdslStatistics(ctx context.Context) ([]XdslStatistics, error) {
+	level.Info(client.logger).Log("msg", "Retrieve xDSL statistics from Bbox")
+	var metrics []XdslStatistics
+	if err := client.apiRequest(ctx, "/wan/xdsl/stats", &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -20,10 +20,15 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	promconfig "github.com/prometheus/common/config"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
 	"github.com/prometheus/common/version"
@@ -31,15 +36,19 @@ import (
 	webflag "github.com/prometheus/exporter-toolkit/web/kingpinflag"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/nlamirault/bbox_exporter/bbox"
+	"github.com/nlamirault/bbox_exporter/config"
 	"github.com/nlamirault/bbox_exporter/exporter"
 )
 
+const defaultEndpoint = "https://mabbox.bytel.fr"
+
 var (
 	webConfig = webflag.AddFlags(kingpin.CommandLine)
 	endpoint  = kingpin.Flag(
 		"endpoint",
-		"Endpoint of Bbox.",
-	).Default("https://mabbox.bytel.fr").OverrideDefaultFromEnvar("BBOX_EXPORTER_ENDPOINT").String()
+		"Endpoint of Bbox (default: "+defaultEndpoint+", unless --config.file is used).",
+	).OverrideDefaultFromEnvar("BBOX_EXPORTER_ENDPOINT").String()
 	password = kingpin.Flag(
 		"password",
 		"The admin password.",
@@ -52,8 +61,113 @@ var (
 		"web.telemetry-path",
 		"Path under which to expose metrics.",
 	).OverrideDefaultFromEnvar("BBOX_EXPORTER_METRICS_PATH").Default("/metrics").String()
+	configFile = kingpin.Flag(
+		"config.file",
+		"Configuration file holding the modules and targets.",
+	).OverrideDefaultFromEnvar("BBOX_EXPORTER_CONFIG_FILE").String()
+	timeoutOffset = kingpin.Flag(
+		"scrape.timeout-offset",
+		"Offset to subtract from the timeout of Prometheus, in seconds.",
+	).Default("0.5").Float64()
+	probeMaxEndpoints = kingpin.Flag(
+		"probe.max-endpoints",
+		"Maximum number of probed endpoints, which are not targets of the configuration file, whose client is kept.",
+	).Default("32").Int()
+	configTarget = kingpin.Flag(
+		"config.target",
+		"Name of the target of the configuration file exposed on the telemetry path, instead of --endpoint and --password.",
+	).OverrideDefaultFromEnvar("BBOX_EXPORTER_CONFIG_TARGET").String()
 )
 
+// newClient creates a client for the Bbox endpoint using the module settings.
+func newClient(endpoint string, module config.Module, logger log.Logger) (*bbox.Client, error) {
+	password, err := module.GetPassword()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := promconfig.NewTLSConfig(&module.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	retry := bbox.DefaultRetryPolicy
+	if module.Retry.MaxAttempts > 0 {
+		retry.MaxAttempts = module.Retry.MaxAttempts
+	}
+	if module.Retry.InitialBackoff > 0 {
+		retry.InitialBackoff = module.Retry.InitialBackoff
+	}
+	if module.Retry.MaxBackoff > 0 {
+		retry.MaxBackoff = module.Retry.MaxBackoff
+	}
+	return bbox.NewClient(endpoint, password, logger,
+		bbox.WithTimeout(module.Timeout),
+		bbox.WithTLSConfig(tlsConfig),
+		bbox.WithRetryPolicy(retry),
+		bbox.WithCircuitBreaker(module.CircuitBreaker.FailureThreshold, module.CircuitBreaker.Cooldown))
+}
+
+// newExporter creates an exporter for the Bbox endpoint using the module settings.
+func newExporter(endpoint string, module config.Module, logger log.Logger) (*exporter.Exporter, error) {
+	client, err := newClient(endpoint, module, logger)
+	if err != nil {
+		return nil, err
+	}
+	return exporter.NewExporter(client, module.Collectors, logger)
+}
+
+// singleTarget holds the exporter exposed on the telemetry path.
+type singleTarget struct {
+	sync.RWMutex
+	exporter *exporter.Exporter
+}
+
+// update replaces the exporter by the new one.
+func (s *singleTarget) update(e *exporter.Exporter) {
+	s.Lock()
+	defer s.Unlock()
+	s.exporter = e
+}
+
+// get returns the current exporter.
+func (s *singleTarget) get() *exporter.Exporter {
+	s.RLock()
+	defer s.RUnlock()
+	return s.exporter
+}
+
+// reload reads the configuration file and recreates the single target exporter
+// when it comes from the configuration.
+func reload(sc *config.SafeConfig, single *singleTarget, cache *clientCache, logger log.Logger) error {
+	if *configFile == "" {
+		return fmt.Errorf("no configuration file")
+	}
+	if err := sc.ReloadConfig(*configFile); err != nil {
+		return err
+	}
+	conf := sc.Get()
+	cache.retain(conf)
+	if *configTarget == "" {
+		return nil
+	}
+	target, ok := conf.Targets[*configTarget]
+	if !ok {
+		return fmt.Errorf("unknown target %q", *configTarget)
+	}
+	// The client is shared with the probes of the target, and kept across
+	// reloads with its state
+	logger = log.With(logger, "target", *configTarget)
+	client, err := cache.get(*configTarget, target.Endpoint, target.Module, true, logger)
+	if err != nil {
+		return err
+	}
+	e, err := exporter.NewExporter(client, target.Module.Collectors, logger)
+	if err != nil {
+		return err
+	}
+	single.update(e)
+	return nil
+}
+
 func main() {
 	// Parse flags.
 	promlogConfig := &promlog.Config{}
@@ -61,38 +175,87 @@ func main() {
 	kingpin.Version(version.Print("bbox_exporter"))
 	kingpin.HelpFlag.Short('h')
 	kingpin.Parse()
+	if *probeMaxEndpoints < 1 {
+		kingpin.Fatalf("--probe.max-endpoints must be at least 1, got %d", *probeMaxEndpoints)
+	}
 	logger := promlog.New(promlogConfig)
 
 	level.Info(logger).Log("msg", "Starting bbox_exporter", "version", version.Info())
 	level.Info(logger).Log("msg", "Build context", "context", version.BuildContext())
 
-	exporter, err := exporter.NewExporter(*endpoint, *password, logger)
-	if err != nil {
-		level.Error(logger).Log("msg", "Can't create exporter", "err", err)
+	if err := exporter.LoadVoipHashKey(); err != nil {
+		level.Error(logger).Log("msg", "Can't load the hash key of the phone lines", "err", err)
 		os.Exit(1)
 	}
-	prometheus.MustRegister(exporter)
 
-	// http.Handle(*metricPath, promhttp.Handler())
+	sc := &config.SafeConfig{C: &config.Config{}, Collectors: exporter.CollectorNames()}
+	single := &singleTarget{}
+	cache := newClientCache(*probeMaxEndpoints)
+	if *configFile != "" {
+		if err := reload(sc, single, cache, logger); err != nil {
+			level.Error(logger).Log("msg", "Error loading config", "err", err)
+			os.Exit(1)
+		}
+		level.Info(logger).Log("msg", "Loaded config file", "file", *configFile)
+	}
+	// With a configuration file, the telemetry path only exposes a Bbox if
+	// one is given, the other ones being probed.
+	if *configTarget == "" && (*configFile == "" || *endpoint != "") {
+		if *endpoint == "" {
+			*endpoint = defaultEndpoint
+		}
+		e, err := newExporter(*endpoint, config.Module{Password: *password}, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "Can't create exporter", "err", err)
+			os.Exit(1)
+		}
+		single.update(e)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reload(sc, single, cache, logger); err != nil {
+				level.Error(logger).Log("msg", "Error reloading config", "err", err)
+				continue
+			}
+			level.Info(logger).Log("msg", "Reloaded config file")
+		}
+	}()
+
 	http.Handle(*metricPath,
 		promhttp.InstrumentMetricHandler(
 			prometheus.DefaultRegisterer,
-			promhttp.HandlerFor(
-				prometheus.DefaultGatherer,
-				promhttp.HandlerOpts{
-					// ErrorLog: &promHTTPLogger{
-					// 	logger: logger,
-					// },
-				},
-			),
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				metricsHandler(w, r, single.get(), logger)
+			}),
 		),
 	)
+	http.HandleFunc("/probe", func(w http.ResponseWriter, r *http.Request) {
+		probeHandler(w, r, sc.Get(), cache, logger)
+	})
+	http.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			fmt.Fprintf(w, "This endpoint requires a POST request.\n")
+			return
+		}
+		if err := reload(sc, single, cache, logger); err != nil {
+			level.Error(logger).Log("msg", "Error reloading config", "err", err)
+			http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+			return
+		}
+		level.Info(logger).Log("msg", "Reloaded config file")
+		fmt.Fprintf(w, "OK")
+	})
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>BBox Exporter</title></head>
              <body>
              <h1>BBox Exporter</h1>
              <p><a href='` + *metricPath + `'>Metrics</a></p>
+             <p><a href='/probe?target=https://mabbox.bytel.fr'>Probe mabbox.bytel.fr</a></p>
 			 <h2>Build</h2>
              <pre>` + version.Info() + ` ` + version.BuildContext() + `</pre>
              </body>
@@ -0,0 +1,130 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+
+	"github.com/go-kit/log"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+	"github.com/nlamirault/bbox_exporter/config"
+)
+
+// clientCache keeps the clients of the probed Bbox, so their sessions are
+// reused between scrapes. The clients of the targets of the configuration
+// file are always kept, while the ones of the other endpoints are evicted,
+// least recently used first, beyond maxEndpoints. A client keeps the state of
+// its Bbox, like the device log already read, so it is only recreated when its
+// settings change.
+type clientCache struct {
+	sync.Mutex
+	clients      map[string]*cachedClient
+	endpoints    *list.List // keys of the endpoints, most recently used first
+	maxEndpoints int
+}
+
+type cachedClient struct {
+	client   *bbox.Client
+	element  *list.Element // nil for the targets of the configuration file
+	endpoint string
+	module   config.Module
+	password string
+}
+
+// sameSettings tells if the client was created with these settings. The
+// collectors don't change the client.
+func (c *cachedClient) sameSettings(endpoint string, module config.Module) bool {
+	module.Collectors = c.module.Collectors
+	return c.endpoint == endpoint && reflect.DeepEqual(c.module, module)
+}
+
+func newClientCache(maxEndpoints int) *clientCache {
+	return &clientCache{
+		clients:      make(map[string]*cachedClient),
+		endpoints:    list.New(),
+		maxEndpoints: maxEndpoints,
+	}
+}
+
+// get returns the client of the key, creating it if needed. configured tells
+// if the key is a target of the configuration file.
+func (c *clientCache) get(key string, endpoint string, module config.Module, configured bool, logger log.Logger) (*bbox.Client, error) {
+	c.Lock()
+	defer c.Unlock()
+	if cached, ok := c.clients[key]; ok {
+		if cached.sameSettings(endpoint, module) {
+			if cached.element != nil {
+				c.endpoints.MoveToFront(cached.element)
+			}
+			return cached.client, nil
+		}
+		c.remove(key)
+	}
+	password, err := module.GetPassword()
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(endpoint, module, logger)
+	if err != nil {
+		return nil, err
+	}
+	cached := &cachedClient{
+		client:   client,
+		endpoint: endpoint,
+		module:   module,
+		password: password,
+	}
+	if !configured {
+		cached.element = c.endpoints.PushFront(key)
+	}
+	c.clients[key] = cached
+	for c.endpoints.Len() > 0 && c.endpoints.Len() > c.maxEndpoints {
+		c.remove(c.endpoints.Back().Value.(string))
+	}
+	return client, nil
+}
+
+// retain drops the clients of the targets removed from the configuration, and
+// the ones whose password file changed. The other clients are kept with their
+// state; get recreates them if their settings changed.
+func (c *clientCache) retain(conf *config.Config) {
+	c.Lock()
+	defer c.Unlock()
+	for key, cached := range c.clients {
+		if cached.element == nil {
+			if _, ok := conf.Targets[key]; !ok {
+				c.remove(key)
+				continue
+			}
+		}
+		if password, err := cached.module.GetPassword(); err != nil || password != cached.password {
+			c.remove(key)
+		}
+	}
+}
+
+// remove closes the client of the key and drops it. It must be called with
+// the lock held.
+func (c *clientCache) remove(key string) {
+	cached := c.clients[key]
+	if cached.element != nil {
+		c.endpoints.Remove(cached.element)
+	}
+	cached.client.Close()
+	delete(c.clients, key)
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+	"github.com/nlamirault/bbox_exporter/config"
+)
+
+func TestClientCache(t *testing.T) {
+	cache := newClientCache(2)
+	get := func(key string, configured bool) *bbox.Client {
+		t.Helper()
+		client, err := cache.get(key, "https://"+key, config.Module{}, configured, log.NewNopLogger())
+		if err != nil {
+			t.Fatalf("can't create client: %s", err)
+		}
+		return client
+	}
+
+	home := get("home", true)
+	first := get("192.168.1.1", false)
+	second := get("192.168.1.2", false)
+	if get("192.168.1.1", false) != first {
+		t.Errorf("client of an endpoint not reused")
+	}
+	// The least recently used endpoint is evicted, not the target
+	get("192.168.1.3", false)
+	if get("192.168.1.1", false) != first {
+		t.Errorf("client of a recently used endpoint evicted")
+	}
+	if get("192.168.1.2", false) == second {
+		t.Errorf("client of the least recently used endpoint kept")
+	}
+	if get("home", true) != home {
+		t.Errorf("client of a target evicted")
+	}
+	if len(cache.clients) != 3 {
+		t.Errorf("expected 3 clients, got %d", len(cache.clients))
+	}
+}
+
+func TestClientCacheRetain(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{
+		Modules: map[string]config.Module{"default": {PasswordFile: passwordFile}},
+		Targets: map[string]config.Target{
+			"home":   {Endpoint: "https://home", Module: config.Module{Password: "secret"}},
+			"office": {Endpoint: "https://office", Module: config.Module{PasswordFile: passwordFile}},
+		},
+	}
+	cache := newClientCache(2)
+	get := func(key string, endpoint string, module config.Module, configured bool) *bbox.Client {
+		t.Helper()
+		client, err := cache.get(key, endpoint, module, configured, log.NewNopLogger())
+		if err != nil {
+			t.Fatalf("can't create client: %s", err)
+		}
+		return client
+	}
+
+	home := get("home", "https://home", conf.Targets["home"].Module, true)
+	office := get("office", "https://office", conf.Targets["office"].Module, true)
+	probed := get("192.168.1.1?module=default", "https://192.168.1.1", conf.Modules["default"], false)
+
+	// An unchanged configuration keeps the clients and their state
+	cache.retain(conf)
+	if get("home", "https://home", conf.Targets["home"].Module, true) != home {
+		t.Errorf("client of an unchanged target recreated")
+	}
+	if get("192.168.1.1?module=default", "https://192.168.1.1", conf.Modules["default"], false) != probed {
+		t.Errorf("client of an unchanged endpoint recreated")
+	}
+	if get("home", "https://home", config.Module{Password: "secret", Collectors: []string{"device"}}, true) != home {
+		t.Errorf("client recreated for other collectors")
+	}
+
+	// The clients are recreated when their settings change
+	if get("home", "https://home", config.Module{Password: "secret", Timeout: time.Second}, true) == home {
+		t.Errorf("client of a modified target kept")
+	}
+	if err := ioutil.WriteFile(passwordFile, []byte("another"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	delete(conf.Targets, "home")
+	cache.retain(conf)
+	if _, ok := cache.clients["home"]; ok {
+		t.Errorf("client of a removed target kept")
+	}
+	if get("office", "https://office", conf.Targets["office"].Module, true) == office {
+		t.Errorf("client kept with a previous password")
+	}
+	if len(cache.clients) != 1 || cache.endpoints.Len() != 0 {
+		t.Errorf("expected 1 client, got %d clients and %d endpoints", len(cache.clients), cache.endpoints.Len())
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	promconfig "github.com/prometheus/common/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Config define the configuration file of the Bbox exporter
+type Config struct {
+	Modules map[string]Module `yaml:"modules"`
+	Targets map[string]Target `yaml:"targets"`
+}
+
+// Module define the settings used to query a Bbox
+type Module struct {
+	Password       string               `yaml:"password"`
+	PasswordFile   string               `yaml:"password_file"`
+	Timeout        time.Duration        `yaml:"timeout"`
+	TLSConfig      promconfig.TLSConfig `yaml:"tls_config"`
+	Collectors     []string             `yaml:"collectors"`
+	Retry          Retry                `yaml:"retry"`
+	CircuitBreaker CircuitBreaker       `yaml:"circuit_breaker"`
+	// AllowUnconfiguredTargets allows to probe any endpoint with the module,
+	// which sends its password to the endpoint
+	AllowUnconfiguredTargets bool `yaml:"allow_unconfigured_targets"`
+}
+
+// Retry define how the requests to a Bbox are retried on transient errors
+type Retry struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// CircuitBreaker define when the requests to an unreachable Bbox are stopped
+type CircuitBreaker struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	Cooldown         time.Duration `yaml:"cooldown"`
+}
+
+// Target define a named Bbox with its own settings
+type Target struct {
+	Endpoint string `yaml:"endpoint"`
+	Module   `yaml:",inline"`
+}
+
+// SafeConfig hold the current configuration, which can be reloaded.
+// Collectors are the names of the available collectors, checked when loading.
+type SafeConfig struct {
+	sync.RWMutex
+	C          *Config
+	Collectors []string
+}
+
+// LoadFile parse and validate the given YAML configuration file. The collectors
+// of the modules must be in the given list, unless it is empty.
+func LoadFile(filename string, collectors []string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read configuration file: %s", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("can't parse configuration file %s: %s", filename, err)
+	}
+	if err := config.Validate(collectors); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %s", filename, err)
+	}
+	return &config, nil
+}
+
+// Validate check the modules and targets of the configuration. The collectors
+// of the modules must be in the given list, unless it is empty.
+func (c *Config) Validate(collectors []string) error {
+	for name, module := range c.Modules {
+		if err := module.validate(collectors); err != nil {
+			return fmt.Errorf("module %q: %s", name, err)
+		}
+	}
+	for name, target := range c.Targets {
+		if target.Endpoint == "" {
+			return fmt.Errorf("target %q: missing endpoint", name)
+		}
+		u, err := url.Parse(target.Endpoint)
+		if err != nil {
+			return fmt.Errorf("target %q: invalid endpoint: %s", name, err)
+		}
+		if u.Scheme != "https" {
+			return fmt.Errorf("target %q: endpoint %s must use https", name, target.Endpoint)
+		}
+		if err := target.Module.validate(collectors); err != nil {
+			return fmt.Errorf("target %q: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (m Module) validate(collectors []string) error {
+	if m.Password != "" && m.PasswordFile != "" {
+		return fmt.Errorf("at most one of password and password_file must be configured")
+	}
+	if m.PasswordFile != "" {
+		if _, err := m.GetPassword(); err != nil {
+			return err
+		}
+	}
+	if m.Timeout < 0 {
+		return fmt.Errorf("timeout must be positive: %s", m.Timeout)
+	}
+	if m.Retry.MaxAttempts < 0 || m.Retry.InitialBackoff < 0 || m.Retry.MaxBackoff < 0 {
+		return fmt.Errorf("retry settings must be positive")
+	}
+	if m.Retry.MaxBackoff > 0 && m.Retry.InitialBackoff > m.Retry.MaxBackoff {
+		return fmt.Errorf("retry initial_backoff %s is greater than max_backoff %s", m.Retry.InitialBackoff, m.Retry.MaxBackoff)
+	}
+	if m.CircuitBreaker.FailureThreshold < 0 || m.CircuitBreaker.Cooldown < 0 {
+		return fmt.Errorf("circuit breaker settings must be positive")
+	}
+	if _, err := promconfig.NewTLSConfig(&m.TLSConfig); err != nil {
+		return fmt.Errorf("invalid TLS configuration: %s", err)
+	}
+	if len(collectors) > 0 {
+		for _, name := range m.Collectors {
+			if !contains(collectors, name) {
+				return fmt.Errorf("unknown collector %q, available collectors: %s", name, strings.Join(collectors, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPassword returns the admin password, reading it from password_file if defined
+func (m Module) GetPassword() (string, error) {
+	if m.PasswordFile == "" {
+		return m.Password, nil
+	}
+	content, err := ioutil.ReadFile(m.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("can't read password file: %s", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// ReloadConfig load the configuration file and replace the current one if valid
+func (sc *SafeConfig) ReloadConfig(filename string) error {
+	config, err := LoadFile(filename, sc.Collectors)
+	if err != nil {
+		return err
+	}
+	sc.Lock()
+	sc.C = config
+	sc.Unlock()
+	return nil
+}
+
+// Get returns the current configuration
+func (sc *SafeConfig) Get() *Config {
+	sc.RLock()
+	defer sc.RUnlock()
+	return sc.C
+}
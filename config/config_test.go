@@ -0,0 +1,154 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCollectors = []string{"device", "wan"}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatalf("can't write %s: %s", filename, err)
+	}
+	return filename
+}
+
+func TestLoadFile(t *testing.T) {
+	passwordFile := writeFile(t, "password", "secret\n")
+	filename := writeFile(t, "bbox.yml", `
+modules:
+  default:
+    password_file: `+passwordFile+`
+    timeout: 5s
+    collectors: [device, wan]
+targets:
+  home:
+    endpoint: https://192.168.1.254
+    password: admin
+    retry:
+      max_attempts: 2
+`)
+	config, err := LoadFile(filename, testCollectors)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	module := config.Modules["default"]
+	if module.Timeout != 5*time.Second {
+		t.Errorf("timeout: got %s", module.Timeout)
+	}
+	if password, err := module.GetPassword(); err != nil || password != "secret" {
+		t.Errorf("password: got %q (%v)", password, err)
+	}
+	target := config.Targets["home"]
+	if target.Endpoint != "https://192.168.1.254" || target.Password != "admin" || target.Retry.MaxAttempts != 2 {
+		t.Errorf("unexpected target: %+v", target)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "unknown field",
+			content: "modules:\n  default:\n    passwd: admin\n",
+			err:     "field passwd not found",
+		},
+		{
+			name:    "password and password file",
+			content: "modules:\n  default:\n    password: admin\n    password_file: /etc/bbox.password\n",
+			err:     "at most one of password and password_file",
+		},
+		{
+			name:    "missing password file",
+			content: "modules:\n  default:\n    password_file: /nonexistent/bbox.password\n",
+			err:     "can't read password file",
+		},
+		{
+			name:    "negative timeout",
+			content: "modules:\n  default:\n    timeout: -1s\n",
+			err:     "timeout must be positive",
+		},
+		{
+			name:    "negative retry",
+			content: "modules:\n  default:\n    retry:\n      max_attempts: -1\n",
+			err:     "retry settings must be positive",
+		},
+		{
+			name:    "retry backoffs",
+			content: "modules:\n  default:\n    retry:\n      initial_backoff: 5s\n      max_backoff: 1s\n",
+			err:     "greater than max_backoff",
+		},
+		{
+			name:    "negative circuit breaker",
+			content: "modules:\n  default:\n    circuit_breaker:\n      cooldown: -1m\n",
+			err:     "circuit breaker settings must be positive",
+		},
+		{
+			name:    "unknown collector",
+			content: "modules:\n  default:\n    collectors: [nope, wan]\n",
+			err:     `module "default": unknown collector "nope"`,
+		},
+		{
+			name:    "unknown collector of a target",
+			content: "targets:\n  home:\n    endpoint: https://192.168.1.254\n    collectors: [nope]\n",
+			err:     `target "home": unknown collector "nope"`,
+		},
+		{
+			name:    "missing endpoint",
+			content: "targets:\n  home:\n    password: admin\n",
+			err:     `target "home": missing endpoint`,
+		},
+		{
+			name:    "http endpoint",
+			content: "targets:\n  home:\n    endpoint: http://192.168.1.254\n",
+			err:     "must use https",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFile(writeFile(t, "bbox.yml", tt.content), testCollectors)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %q doesn't contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReloadConfigKeepsValidConfig(t *testing.T) {
+	sc := &SafeConfig{C: &Config{}, Collectors: testCollectors}
+	if err := sc.ReloadConfig(writeFile(t, "bbox.yml", "modules:\n  default:\n    password: admin\n")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := sc.ReloadConfig(writeFile(t, "bbox.yml", "modules:\n  default:\n    collectors: [nope]\n")); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if sc.Get().Modules["default"].Password != "admin" {
+		t.Errorf("configuration replaced by an invalid one")
+	}
+}
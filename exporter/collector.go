@@ -0,0 +1,288 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+const (
+	defaultEnabled  = true
+	defaultDisabled = false
+)
+
+var (
+	scrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
+		"Duration of a collector scrape.",
+		[]string{"collector"}, nil,
+	)
+	scrapeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+		"Whether a collector succeeded.",
+		[]string{"collector"}, nil,
+	)
+	scrapeErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_error"),
+		"Reason of the failure of a collector.",
+		[]string{"collector", "reason"}, nil,
+	)
+)
+
+// errCollectorPanic is returned by a collector which panicked.
+var errCollectorPanic = errors.New("collector panic")
+
+var (
+	factories      = make(map[string]func(logger log.Logger) Collector)
+	collectorState = make(map[string]*bool)
+
+	maxParallelism = kingpin.Flag(
+		"scrape.max-parallelism",
+		"Maximum number of collectors querying the Bbox at the same time.",
+	).Default("4").Int()
+)
+
+// Collector is the interface a collector has to implement.
+type Collector interface {
+	// Describe sends the descriptors of the metrics of the collector.
+	Describe(ch chan<- *prometheus.Desc)
+	// Update gets new metrics from the Bbox and exposes them via the channel.
+	Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error
+}
+
+// registerCollector makes a collector available, with its --collector.<name> flag.
+func registerCollector(name string, isDefaultEnabled bool, factory func(logger log.Logger) Collector) {
+	var helpDefaultState string
+	if isDefaultEnabled {
+		helpDefaultState = "enabled"
+	} else {
+		helpDefaultState = "disabled"
+	}
+
+	flagName := fmt.Sprintf("collector.%s", name)
+	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", name, helpDefaultState)
+	defaultValue := fmt.Sprintf("%v", isDefaultEnabled)
+
+	flag := kingpin.Flag(flagName, flagHelp).Default(defaultValue).Bool()
+	collectorState[name] = flag
+	factories[name] = factory
+}
+
+// CollectorNames returns the sorted names of the available collectors.
+func CollectorNames() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCollectors creates the given collectors, or the ones enabled by the
+// command line flags if the list is empty.
+func newCollectors(names []string, logger log.Logger) (map[string]Collector, error) {
+	if len(names) == 0 {
+		for name, enabled := range collectorState {
+			if *enabled {
+				names = append(names, name)
+			}
+		}
+	}
+	collectors := make(map[string]Collector)
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector: %s", name)
+		}
+		collectors[name] = factory(log.With(logger, "collector", name))
+	}
+	return collectors, nil
+}
+
+type collectorResult struct {
+	name     string
+	metrics  []prometheus.Metric
+	duration time.Duration
+	err      error
+}
+
+// execute runs the enabled collectors concurrently, at most maxParallelism at
+// the same time, until the context is done. Collectors which didn't finish
+// before the deadline are reported as failed.
+func execute(ctx context.Context, collectors map[string]Collector, client *bbox.Client, ch chan<- prometheus.Metric, logger log.Logger) {
+	begin := time.Now()
+	results := make(chan collectorResult, len(collectors))
+	parallelism := *maxParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	pending := make(map[string]bool, len(collectors))
+	for name, c := range collectors {
+		pending[name] = true
+		go func(name string, c Collector) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results <- collectorResult{name: name, err: ctx.Err()}
+				return
+			}
+			defer func() { <-sem }()
+			results <- update(ctx, name, c, client, logger)
+		}(name, c)
+	}
+
+	collect := func(result collectorResult) {
+		delete(pending, result.name)
+		for _, m := range result.metrics {
+			ch <- m
+		}
+		report(ch, result, logger)
+	}
+	for len(pending) > 0 {
+		select {
+		case result := <-results:
+			collect(result)
+		case <-ctx.Done():
+			// The results sent before the deadline are kept, as select picks
+			// randomly between them and the deadline
+			for drained := false; !drained && len(pending) > 0; {
+				select {
+				case result := <-results:
+					collect(result)
+				default:
+					drained = true
+				}
+			}
+			for name := range pending {
+				report(ch, collectorResult{name: name, duration: time.Since(begin), err: ctx.Err()}, logger)
+			}
+			return
+		}
+	}
+}
+
+// update runs the collector, keeping its metrics until it's finished. A series
+// sent twice is dropped, as it would fail the whole scrape.
+func update(ctx context.Context, name string, c Collector, client *bbox.Client, logger log.Logger) collectorResult {
+	begin := time.Now()
+	metricsCh := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	result := collectorResult{name: name}
+	go func() {
+		seen := make(map[string]bool)
+		duplicates := 0
+		for m := range metricsCh {
+			key := metricKey(m)
+			if seen[key] {
+				duplicates++
+				continue
+			}
+			seen[key] = true
+			result.metrics = append(result.metrics, m)
+		}
+		if duplicates > 0 {
+			level.Warn(logger).Log("msg", "Duplicate series dropped", "name", name, "count", duplicates)
+		}
+		close(done)
+	}()
+	err := safeUpdate(ctx, c, client, metricsCh)
+	close(metricsCh)
+	<-done
+	result.duration = time.Since(begin)
+	result.err = err
+	return result
+}
+
+// safeUpdate runs the collector, turning a panic into an error so the other
+// collectors are still exposed.
+func safeUpdate(ctx context.Context, c Collector, client *bbox.Client, ch chan<- prometheus.Metric) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", errCollectorPanic, r)
+		}
+	}()
+	return c.Update(ctx, client, ch)
+}
+
+// metricKey identifies the series of a metric.
+func metricKey(m prometheus.Metric) string {
+	var metric dto.Metric
+	if err := m.Write(&metric); err != nil {
+		return m.Desc().String()
+	}
+	labels := make([]string, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		labels = append(labels, label.GetName()+"="+label.GetValue())
+	}
+	return m.Desc().String() + "|" + strings.Join(labels, ",")
+}
+
+// report exposes the success and duration of a collector.
+func report(ch chan<- prometheus.Metric, result collectorResult, logger log.Logger) {
+	var success float64
+	if result.err != nil {
+		reason := errorReason(result.err)
+		level.Error(logger).Log("msg", "Collector failed", "name", result.name, "duration_seconds", result.duration.Seconds(), "reason", reason, "err", result.err)
+		ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, 1, result.name, reason)
+		success = 0
+	} else {
+		level.Debug(logger).Log("msg", "Collector succeeded", "name", result.name, "duration_seconds", result.duration.Seconds())
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, result.duration.Seconds(), result.name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, result.name)
+}
+
+// errorReason classifies the errors of the Bbox client.
+func errorReason(err error) string {
+	var decodeError *bbox.DecodeError
+	var apiError *bbox.APIError
+	var urlError *url.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.Is(err, errCollectorPanic):
+		return "panic"
+	case errors.Is(err, bbox.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, bbox.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, bbox.ErrRateLimited):
+		return "rate_limited"
+	case errors.As(err, &decodeError):
+		return "decode"
+	case errors.As(err, &apiError):
+		return "api"
+	case errors.As(err, &urlError):
+		return "unreachable"
+	default:
+		return "unknown"
+	}
+}
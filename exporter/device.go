@@ -15,6 +15,9 @@
 package exporter
 
 import (
+	"context"
+
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
@@ -66,6 +69,36 @@ var (
 	)
 )
 
+func init() {
+	registerCollector("device", defaultEnabled, NewDeviceCollector)
+}
+
+type deviceCollector struct {
+	logger log.Logger
+}
+
+// NewDeviceCollector returns a new Collector exposing device metrics.
+func NewDeviceCollector(logger log.Logger) Collector {
+	return &deviceCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *deviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeDeviceMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *deviceCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetDeviceMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeDeviceMetrics(ch, *metrics)
+	return nil
+}
+
 func describeDeviceMetrics(ch chan<- *prometheus.Desc) {
 	ch <- deviceModelName
 	ch <- deviceUsing
@@ -78,26 +111,32 @@ func describeDeviceMetrics(ch chan<- *prometheus.Desc) {
 }
 
 func storeDeviceMetrics(ch chan<- prometheus.Metric, metrics bbox.DeviceMetrics) {
-	storeMetric(ch, 1.0, deviceModelName, metrics.Informations[0].Device.ModelName)
-	storeMetric(ch, float64(metrics.Informations[0].Device.Using.IPv4), deviceUsing, "ipv4")
-	storeMetric(ch, float64(metrics.Informations[0].Device.Using.IPv6), deviceUsing, "ipv6")
-	storeMetric(ch, float64(metrics.Informations[0].Device.Using.FTTH), deviceUsing, "ftth")
-	storeMetric(ch, float64(metrics.Informations[0].Device.Using.ADSL), deviceUsing, "adsl")
-	storeMetric(ch, float64(metrics.Informations[0].Device.Using.VDSL), deviceUsing, "vdsl")
-	storeMetric(ch, metrics.Informations[0].Device.Status, deviceStatus)
-	storeMetric(ch, metrics.Informations[0].Device.NumberOfBoots, deviceNumberOfBoots)
-	storeMetric(ch, metrics.Informations[0].Device.Temperature.Current, deviceTemperature)
-	storeMetric(ch, metrics.Memory[0].Device.Memory.Total, deviceMemory, "total")
-	storeMetric(ch, metrics.Memory[0].Device.Memory.Free, deviceMemory, "free")
-	storeMetric(ch, metrics.Memory[0].Device.Memory.Cached, deviceMemory, "cached")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Total, deviceCPU, "total")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.User, deviceCPU, "user")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Nice, deviceCPU, "nice")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.System, deviceCPU, "system")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.IO, deviceCPU, "io")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Idle, deviceCPU, "idle")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Irq, deviceCPU, "irq")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Created, deviceProcess, "created")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Running, deviceProcess, "running")
-	storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Blocked, deviceProcess, "blocked")
+	if len(metrics.Informations) > 0 {
+		storeMetric(ch, 1.0, deviceModelName, metrics.Informations[0].Device.ModelName)
+		storeMetric(ch, float64(metrics.Informations[0].Device.Using.IPv4), deviceUsing, "ipv4")
+		storeMetric(ch, float64(metrics.Informations[0].Device.Using.IPv6), deviceUsing, "ipv6")
+		storeMetric(ch, float64(metrics.Informations[0].Device.Using.FTTH), deviceUsing, "ftth")
+		storeMetric(ch, float64(metrics.Informations[0].Device.Using.ADSL), deviceUsing, "adsl")
+		storeMetric(ch, float64(metrics.Informations[0].Device.Using.VDSL), deviceUsing, "vdsl")
+		storeMetric(ch, metrics.Informations[0].Device.Status, deviceStatus)
+		storeMetric(ch, metrics.Informations[0].Device.NumberOfBoots, deviceNumberOfBoots)
+		storeMetric(ch, metrics.Informations[0].Device.Temperature.Current, deviceTemperature)
+	}
+	if len(metrics.Memory) > 0 {
+		storeMetric(ch, metrics.Memory[0].Device.Memory.Total, deviceMemory, "total")
+		storeMetric(ch, metrics.Memory[0].Device.Memory.Free, deviceMemory, "free")
+		storeMetric(ch, metrics.Memory[0].Device.Memory.Cached, deviceMemory, "cached")
+	}
+	if len(metrics.CPU) > 0 {
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Total, deviceCPU, "total")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.User, deviceCPU, "user")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Nice, deviceCPU, "nice")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.System, deviceCPU, "system")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.IO, deviceCPU, "io")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Idle, deviceCPU, "idle")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Time.Irq, deviceCPU, "irq")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Created, deviceProcess, "created")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Running, deviceProcess, "running")
+		storeMetric(ch, metrics.CPU[0].Device.CPU.Process.Blocked, deviceProcess, "blocked")
+	}
 }
@@ -0,0 +1,97 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	deviceLogForward = kingpin.Flag(
+		"collector.devicelog.forward",
+		"Log the new entries of the log of the Bbox.",
+	).Default("false").Bool()
+)
+
+var (
+	deviceLogEvents = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "device_log_events_total"),
+		"Number of events in the log of the Bbox",
+		[]string{"type", "severity"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("devicelog", defaultEnabled, NewDeviceLogCollector)
+}
+
+type deviceLogCollector struct {
+	logger log.Logger
+}
+
+// NewDeviceLogCollector returns a new Collector exposing the events of the log of the Bbox.
+func NewDeviceLogCollector(logger log.Logger) Collector {
+	return &deviceLogCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *deviceLogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- deviceLogEvents
+}
+
+// Update implements the Collector interface.
+func (c *deviceLogCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	entries, err := client.ReadDeviceLog(ctx)
+	if err != nil {
+		return err
+	}
+	if *deviceLogForward {
+		for _, entry := range entries {
+			level.Info(c.logger).Log("msg", "Bbox log entry",
+				"date", entry.Date,
+				"type", deviceLogLabel(entry.Log),
+				"severity", deviceLogLabel(entry.Severity),
+				"param", entry.Param)
+		}
+	}
+	// Events differing only by the case have the same labels
+	counts := make(map[[2]string]uint64)
+	for event, count := range client.DeviceLogEvents() {
+		counts[[2]string{deviceLogLabel(event.Type), deviceLogLabel(event.Severity)}] += count
+	}
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			deviceLogEvents, prometheus.CounterValue, float64(count), labels[0], labels[1])
+	}
+	return nil
+}
+
+// deviceLogLabel returns the label of a field of a log entry
+func deviceLogLabel(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	dhcpEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_enabled"),
+		"Is the DHCP server enabled",
+		nil, nil,
+	)
+	dhcpPoolSize = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_pool_size"),
+		"Number of addresses of the DHCP pool",
+		nil, nil,
+	)
+	dhcpLeases = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_leases"),
+		"Number of dynamic leases in use in the DHCP pool",
+		nil, nil,
+	)
+	dhcpPoolUtilization = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_pool_utilization_ratio"),
+		"Ratio of the DHCP pool in use, from 0 to 1",
+		nil, nil,
+	)
+	dhcpLeaseTime = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_lease_time_seconds"),
+		"Lease time of the DHCP server",
+		nil, nil,
+	)
+	dhcpStaticLeases = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "dhcp_static_leases"),
+		"Number of enabled static leases",
+		nil, nil,
+	)
+)
+
+func init() {
+	registerCollector("dhcp", defaultEnabled, NewDhcpCollector)
+}
+
+type dhcpCollector struct {
+	logger log.Logger
+}
+
+// NewDhcpCollector returns a new Collector exposing DHCP server metrics.
+func NewDhcpCollector(logger log.Logger) Collector {
+	return &dhcpCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *dhcpCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dhcpEnabled
+	ch <- dhcpPoolSize
+	ch <- dhcpLeases
+	ch <- dhcpPoolUtilization
+	ch <- dhcpLeaseTime
+	ch <- dhcpStaticLeases
+}
+
+// Update implements the Collector interface.
+func (c *dhcpCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetDhcpMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeDhcpMetrics(c.logger, ch, *metrics)
+	return nil
+}
+
+func storeDhcpMetrics(logger log.Logger, ch chan<- prometheus.Metric, metrics bbox.DhcpMetrics) {
+	staticLeases := 0
+	for _, client := range metrics.Clients {
+		if client.Enable != 0 {
+			staticLeases++
+		}
+	}
+	storeMetric(ch, float64(staticLeases), dhcpStaticLeases)
+
+	if len(metrics.Informations) == 0 {
+		level.Warn(logger).Log("msg", "No informations for DHCP")
+		return
+	}
+	dhcp := metrics.Informations[0].Dhcp
+	storeMetric(ch, float64(dhcp.Enable), dhcpEnabled)
+	storeMetric(ch, float64(dhcp.Lease), dhcpLeaseTime)
+
+	min, minOk := ipv4ToInt(dhcp.MinAddress)
+	max, maxOk := ipv4ToInt(dhcp.MaxAddress)
+	if !minOk || !maxOk || max < min {
+		level.Warn(logger).Log("msg", "Invalid DHCP pool", "min", dhcp.MinAddress, "max", dhcp.MaxAddress)
+		return
+	}
+	size := float64(max - min + 1)
+	// A dynamic lease is in use until it expires, even if the device is gone
+	leases := 0
+	for _, host := range metrics.Hosts {
+		ip, ok := ipv4ToInt(host.Ipaddress)
+		if host.Type == "DHCP" && ok && ip >= min && ip <= max && (host.Lease > 0 || host.Active != 0) {
+			leases++
+		}
+	}
+	storeMetric(ch, size, dhcpPoolSize)
+	storeMetric(ch, float64(leases), dhcpLeases)
+	storeMetric(ch, float64(leases)/size, dhcpPoolUtilization)
+}
+
+// ipv4ToInt converts an IPv4 address to compare it with the DHCP pool.
+func ipv4ToInt(address string) (uint32, bool) {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ip), true
+}
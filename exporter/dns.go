@@ -15,6 +15,9 @@
 package exporter
 
 import (
+	"context"
+
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
@@ -43,6 +46,36 @@ var (
 	)
 )
 
+func init() {
+	registerCollector("dns", defaultEnabled, NewDNSCollector)
+}
+
+type dnsCollector struct {
+	logger log.Logger
+}
+
+// NewDNSCollector returns a new Collector exposing dns metrics.
+func NewDNSCollector(logger log.Logger) Collector {
+	return &dnsCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *dnsCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeDNSMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *dnsCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetDNSMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeDNSMetrics(ch, *metrics)
+	return nil
+}
+
 func describeDNSMetrics(ch chan<- *prometheus.Desc) {
 	ch <- dnsNumberOfQueries
 	ch <- dnsMin
@@ -51,6 +84,9 @@ func describeDNSMetrics(ch chan<- *prometheus.Desc) {
 }
 
 func storeDNSMetrics(ch chan<- prometheus.Metric, metrics bbox.DNSMetrics) {
+	if len(metrics.Principal) == 0 {
+		return
+	}
 	storeMetric(ch, metrics.Principal[0].DNS.NumberOfQueries, dnsNumberOfQueries)
 	storeMetric(ch, metrics.Principal[0].DNS.Min, dnsMin)
 	storeMetric(ch, metrics.Principal[0].DNS.Max, dnsMax)
@@ -15,6 +15,8 @@
 package exporter
 
 import (
+	"context"
+
 	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -29,42 +31,78 @@ const (
 var (
 	up = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "up"),
-		"Was the last query of BBox successful.",
+		"Was the authentication on the BBox successful.",
+		nil, nil,
+	)
+	loginAttempts = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "login_attempts_total"),
+		"Number of authentications on the BBox API.",
+		nil, nil,
+	)
+	loginFailures = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "login_failures_total"),
+		"Number of failed authentications on the BBox API.",
+		nil, nil,
+	)
+	circuitBreakerState = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "circuit_breaker_state"),
+		"State of the circuit breaker of the BBox API: 0 closed, 1 open, 2 half-open.",
 		nil, nil,
 	)
+	loginError = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "login_error"),
+		"Reason of the failure of the authentication on the BBox API.",
+		[]string{"reason"}, nil,
+	)
 )
 
 // Exporter collects Bbox stats from the given server and exports them using
 // the prometheus metrics package.
 type Exporter struct {
-	Bbox   *bbox.Client
-	logger log.Logger
+	Bbox       *bbox.Client
+	collectors map[string]Collector
+	ctx        context.Context
+	logger     log.Logger
 }
 
-// NewExporter returns an initialized Exporter.
-func NewExporter(endpoint string, password string, logger log.Logger) (*Exporter, error) {
+// NewExporter returns an initialized Exporter. An empty list of collectors
+// uses the ones enabled by the command line flags.
+func NewExporter(bboxClient *bbox.Client, collectors []string, logger log.Logger) (*Exporter, error) {
 	level.Info(logger).Log("msg", "Setup BBox exporter")
-	bboxClient, err := bbox.NewClient(endpoint, password, logger)
+	enabled, err := newCollectors(collectors, logger)
 	if err != nil {
 		return nil, err
 	}
 	return &Exporter{
-		Bbox:   bboxClient,
-		logger: logger,
+		Bbox:       bboxClient,
+		collectors: enabled,
+		ctx:        context.Background(),
+		logger:     logger,
 	}, nil
 }
 
+// WithContext returns a copy of the exporter whose scrapes stop when the
+// context is done.
+func (e *Exporter) WithContext(ctx context.Context) *Exporter {
+	exporter := *e
+	exporter.ctx = ctx
+	return &exporter
+}
+
 // Describe describes all the metrics ever exported by the Bbox exporter.
 // It implements prometheus.Collector.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- up
-	describeWanMetrics(ch)
-	describeLanMetrics(ch)
-	describeDeviceMetrics(ch)
-	describeDNSMetrics(ch)
-	describeIPTVMetrics(ch)
-	describeServicesMetrics(ch)
-	describeWirelessMetrics(ch)
+	ch <- loginAttempts
+	ch <- loginFailures
+	ch <- loginError
+	ch <- circuitBreakerState
+	ch <- scrapeDurationDesc
+	ch <- scrapeSuccessDesc
+	ch <- scrapeErrorDesc
+	for _, c := range e.collectors {
+		c.Describe(ch)
+	}
 }
 
 // Collect the stats from channel and delivers them as Prometheus metrics.
@@ -72,38 +110,41 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	level.Info(e.logger).Log("msg", "Bbox exporter starting")
 
-	if err := e.Bbox.Authenticate(); err != nil {
+	defer e.collectLoginMetrics(ch)
+	if err := e.Bbox.Connect(e.ctx); err != nil {
+		reason := errorReason(err)
 		ch <- prometheus.MustNewConstMetric(
 			up, prometheus.GaugeValue, 0,
 		)
-		level.Error(e.logger).Log("msg", "Bbox authentication error", "err", err.Error())
-		return
-	}
-
-	resp, err := e.Bbox.GetMetrics()
-	if err != nil {
 		ch <- prometheus.MustNewConstMetric(
-			up, prometheus.GaugeValue, 0,
+			loginError, prometheus.GaugeValue, 1, reason,
 		)
-		level.Error(e.logger).Log("msg", "Bbox API error", "err", err.Error())
+		level.Error(e.logger).Log("msg", "Bbox authentication error", "reason", reason, "err", err.Error())
 		return
 	}
 
-	level.Info(e.logger).Log("msg", "Bbox metrics retrieved")
-	storeServicesMetrics(ch, resp.Services)
-	storeDeviceMetrics(ch, resp.Device)
-	storeDNSMetrics(ch, resp.DNS)
-	storeLanMetrics(e.logger, ch, resp.Lan)
-	storeWanMetrics(ch, resp.Wan)
-	storeWanFtthMetric(ch, resp.FtthState)
-	storeWirelessMetrics(ch, resp.Wireless)
-	storeIPTVMetrics(ch, resp.IPTV)
+	execute(e.ctx, e.collectors, e.Bbox, ch, e.logger)
+
 	ch <- prometheus.MustNewConstMetric(
 		up, prometheus.GaugeValue, 1,
 	)
 	level.Info(e.logger).Log("msg", "Metrics collection finished")
 }
 
+// collectLoginMetrics exposes the authentications of the client, including the
+// ones done during the scrape when the session expired, and its circuit breaker.
+func (e *Exporter) collectLoginMetrics(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		loginAttempts, prometheus.CounterValue, float64(e.Bbox.LoginAttempts()),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		loginFailures, prometheus.CounterValue, float64(e.Bbox.LoginFailures()),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		circuitBreakerState, prometheus.GaugeValue, float64(e.Bbox.CircuitBreakerState()),
+	)
+}
+
 func storeMetric(ch chan<- prometheus.Metric, value float64, desc *prometheus.Desc, labels ...string) {
 	ch <- prometheus.MustNewConstMetric(
 		desc, prometheus.GaugeValue, value, labels...)
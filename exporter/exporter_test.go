@@ -0,0 +1,656 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+)
+
+func TestMain(m *testing.M) {
+	// Flags have their default value once parsed, like --scrape.max-parallelism
+	if _, err := kingpin.CommandLine.Parse([]string{}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestExporter(t *testing.T, server *bboxtest.Server, collectors ...string) *Exporter {
+	t.Helper()
+	client, err := bbox.NewClient(server.URL, bboxtest.DefaultPassword, log.NewNopLogger(),
+		bbox.WithHTTPClient(server.Client()),
+		bbox.WithRetryPolicy(bbox.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("can't create client: %s", err)
+	}
+	if len(collectors) == 0 {
+		collectors = CollectorNames()
+	}
+	e, err := NewExporter(client, collectors, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("can't create exporter: %s", err)
+	}
+	return e
+}
+
+// scrape returns the metrics of the exporter, keyed by name{labels}.
+func scrape(t *testing.T, collector prometheus.Collector) map[string]float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("can't register exporter: %s", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("can't gather metrics: %s", err)
+	}
+	metrics := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, label := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", label.GetName(), label.GetValue()))
+			}
+			sort.Strings(labels)
+			key := fmt.Sprintf("%s{%s}", family.GetName(), strings.Join(labels, ","))
+			switch {
+			case m.GetGauge() != nil:
+				metrics[key] = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				metrics[key] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	return metrics
+}
+
+func assertMetric(t *testing.T, metrics map[string]float64, key string, expected float64) {
+	t.Helper()
+	value, ok := metrics[key]
+	if !ok {
+		t.Errorf("missing metric %s", key)
+		return
+	}
+	if value != expected {
+		t.Errorf("metric %s: expected %v, got %v", key, expected, value)
+	}
+}
+
+func TestExporterCollect(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server))
+	assertMetric(t, metrics, "bbox_up{}", 1)
+	for _, name := range CollectorNames() {
+		assertMetric(t, metrics, fmt.Sprintf("bbox_scrape_collector_success{collector=%q}", name), 1)
+	}
+	assertMetric(t, metrics, "bbox_device_temperature{}", 52)
+	assertMetric(t, metrics, `bbox_device_memory{type="total"}`, 246280)
+	assertMetric(t, metrics, "bbox_wan_received_bytes{}", 118392734712)
+	assertMetric(t, metrics, `bbox_lan_connected_devices{link="Ethernet"}`, 1)
+	assertMetric(t, metrics, "bbox_dns_average{}", 24)
+	assertMetric(t, metrics, `bbox_wireless_received_bytes{frequency="5ghz"}`, 598765432)
+	assertMetric(t, metrics, `bbox_wireless_channel{band="24ghz"}`, 11)
+	assertMetric(t, metrics, `bbox_wireless_channel_width_mhz{band="5ghz"}`, 80)
+	assertMetric(t, metrics, `bbox_wireless_ssid_info{band="5ghz",encryption="AES",hidden="true",security="WPA2+WPA3",ssid="Bbox-1A2B3C-5G"}`, 1)
+}
+
+func TestExporterMissingWirelessBand(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.RemoveResponse("/wireless/5/stats")
+
+	metrics := scrape(t, newTestExporter(t, server, "wireless"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="wireless"}`, 1)
+	if _, ok := metrics[`bbox_wireless_received_bytes{frequency="5ghz"}`]; ok {
+		t.Errorf("unexpected metric for the missing 5 GHz band")
+	}
+	assertMetric(t, metrics, `bbox_wireless_received_bytes{frequency="24ghz"}`, 2498765432)
+}
+
+func TestExporterMissingWirelessInformations(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.RemoveResponse("/wireless")
+
+	metrics := scrape(t, newTestExporter(t, server, "wireless"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="wireless"}`, 1)
+	assertMetric(t, metrics, `bbox_wireless_received_bytes{frequency="5ghz"}`, 598765432)
+
+	// The statistics are kept if the configuration fails
+	server.SetResponse("/wireless", []byte(`{"wireless":`))
+	metrics = scrape(t, newTestExporter(t, server, "wireless"))
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="wireless",reason="decode"}`, 1)
+	assertMetric(t, metrics, `bbox_wireless_received_bytes{frequency="24ghz"}`, 2498765432)
+}
+
+func TestExporterPartialFailure(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.SetFault("/iptv", bboxtest.Fault{StatusCode: http.StatusInternalServerError})
+	server.SetFault("/dns/stats", bboxtest.Fault{Body: `[{"dns": `})
+
+	metrics := scrape(t, newTestExporter(t, server))
+	assertMetric(t, metrics, "bbox_up{}", 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="iptv"}`, 0)
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="iptv",reason="api"}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="dns"}`, 0)
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="dns",reason="decode"}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="device"}`, 1)
+	assertMetric(t, metrics, "bbox_device_temperature{}", 52)
+}
+
+func TestExporterStringNumbers(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.SetFault("/wan/ip/stats", bboxtest.Fault{StringNumbers: []string{"bytes", "packets"}})
+
+	metrics := scrape(t, newTestExporter(t, server, "wan"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="wan"}`, 1)
+	assertMetric(t, metrics, "bbox_wan_received_bytes{}", 118392734712)
+	assertMetric(t, metrics, "bbox_wan_transmitted_packets{}", 40128733)
+
+	server.SetFault("/services", bboxtest.Fault{StringNumbers: []string{"enable", "status", "nbrules", "port", "activable"}})
+	metrics = scrape(t, newTestExporter(t, server, "services"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="services"}`, 1)
+	assertMetric(t, metrics, `bbox_service_running{name="firewall"}`, 1)
+	assertMetric(t, metrics, `bbox_service_rules{name="nat"}`, 3)
+	assertMetric(t, metrics, `bbox_service_remote_admin_info{activable="1",duration="",port="8560"}`, 1)
+}
+
+func TestExporterBadPassword(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.Password = "another"
+
+	metrics := scrape(t, newTestExporter(t, server))
+	assertMetric(t, metrics, "bbox_up{}", 0)
+	assertMetric(t, metrics, `bbox_login_error{reason="unauthorized"}`, 1)
+	assertMetric(t, metrics, "bbox_login_failures_total{}", 1)
+}
+
+func TestExporterSessionReuse(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	e := newTestExporter(t, server)
+
+	scrape(t, e)
+	scrape(t, e)
+	if logins := server.Logins(); logins != 1 {
+		t.Errorf("expected 1 login, got %d", logins)
+	}
+
+	server.ExpireSessions()
+	metrics := scrape(t, e)
+	if logins := server.Logins(); logins != 2 {
+		t.Errorf("expected 2 logins after the session expired, got %d", logins)
+	}
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="lan"}`, 1)
+	assertMetric(t, metrics, "bbox_login_attempts_total{}", 2)
+}
+
+func TestExporterTimeout(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.SetFault("/device", bboxtest.Fault{Latency: 5 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	metrics := scrape(t, newTestExporter(t, server, "device", "dns").WithContext(ctx))
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("scrape didn't stop at the deadline: %s", elapsed)
+	}
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="device"}`, 0)
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="device",reason="timeout"}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="dns"}`, 1)
+}
+
+func TestExporterDisabledCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	scrape(t, newTestExporter(t, server, "device"))
+	if requests := server.Requests("/hosts"); requests != 0 {
+		t.Errorf("disabled collector sent %d requests", requests)
+	}
+	if requests := server.Requests("/device"); requests != 1 {
+		t.Errorf("expected 1 request for the device collector, got %d", requests)
+	}
+}
+
+func TestXdslCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "xdsl"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="xdsl"}`, 1)
+	if requests := server.Requests("/wan/xdsl"); requests != 0 {
+		t.Errorf("xDSL queried on a FTTH Bbox: %d requests", requests)
+	}
+
+	device := strings.Replace(string(bboxtest.Fixture("device.json")), `"vdsl": 0`, `"vdsl": 1`, 1)
+	server.SetResponse("/device", []byte(device))
+	metrics = scrape(t, newTestExporter(t, server, "xdsl"))
+	assertMetric(t, metrics, "bbox_wan_xdsl_connected{}", 1)
+	assertMetric(t, metrics, `bbox_wan_xdsl_info{modulation="VDSL2",profile="17a"}`, 1)
+	assertMetric(t, metrics, `bbox_wan_xdsl_sync_rate_kbps{direction="down"}`, 52340)
+	assertMetric(t, metrics, `bbox_wan_xdsl_snr_margin_db{direction="up"}`, 7.2)
+	assertMetric(t, metrics, `bbox_wan_xdsl_errors_total{side="local",type="fec"}`, 34567)
+}
+
+func TestXdslCollectorWanLinks(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	e := newTestExporter(t, server, "xdsl")
+
+	// The technologies of the WAN are retrieved once per session
+	scrape(t, e)
+	scrape(t, e)
+	if requests := server.Requests("/device"); requests != 1 {
+		t.Errorf("expected 1 request for the device, got %d", requests)
+	}
+}
+
+func TestFtthCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "ftth"))
+	assertMetric(t, metrics, "bbox_wan_ftth_state{}", 1)
+	assertMetric(t, metrics, `bbox_wan_ftth_info{mode="GPON"}`, 1)
+	assertMetric(t, metrics, "bbox_wan_ftth_ont_state{}", 5)
+	assertMetric(t, metrics, "bbox_wan_ftth_sfp_rx_power_dbm{}", -18.42)
+	assertMetric(t, metrics, "bbox_wan_ftth_sfp_tx_power_dbm{}", 2.31)
+
+	// Firmwares without optical data only send the state
+	server.SetResponse("/wan/ftth/stats", []byte(`[{"wan":{"ftth":{"mode":"GPON","state":"Down"}}}]`))
+	metrics = scrape(t, newTestExporter(t, server, "ftth"))
+	assertMetric(t, metrics, "bbox_wan_ftth_state{}", 0)
+	if _, ok := metrics["bbox_wan_ftth_sfp_rx_power_dbm{}"]; ok {
+		t.Errorf("unexpected optical metric")
+	}
+
+	// Other ones send empty strings
+	server.SetResponse("/wan/ftth/stats", []byte(`[{"wan":{"ftth":{"mode":"GPON","state":"Up","sfp":{"rx_power":"","tx_power":"2.31","temperature":""}}}}]`))
+	metrics = scrape(t, newTestExporter(t, server, "ftth"))
+	assertMetric(t, metrics, "bbox_wan_ftth_sfp_tx_power_dbm{}", 2.31)
+	for _, key := range []string{"bbox_wan_ftth_sfp_rx_power_dbm{}", "bbox_wan_ftth_sfp_temperature_celsius{}"} {
+		if _, ok := metrics[key]; ok {
+			t.Errorf("unexpected optical metric for an empty value: %s", key)
+		}
+	}
+}
+
+func TestVoipCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "voip"))
+	assertMetric(t, metrics, `bbox_voip_line_registered{line="1"}`, 1)
+	assertMetric(t, metrics, `bbox_voip_line_registered{line="2"}`, 0)
+	assertMetric(t, metrics, `bbox_voip_calls{direction="in",line="1",status="answered"}`, 1)
+	assertMetric(t, metrics, `bbox_voip_calls{direction="in",line="1",status="missed"}`, 1)
+	assertMetric(t, metrics, `bbox_voip_calls{direction="out",line="1",status="answered"}`, 1)
+	assertMetric(t, metrics, `bbox_voip_calls{direction="out",line="2",status="missed"}`, 0)
+	assertMetric(t, metrics, `bbox_voip_calls_duration_seconds{direction="out",line="1"}`, 312)
+	for key := range metrics {
+		if strings.Contains(key, "0123456789") || strings.Contains(key, "0601020304") {
+			t.Errorf("phone number exported: %s", key)
+		}
+		if strings.HasPrefix(key, "bbox_voip_line_info") {
+			t.Errorf("URI exported by default: %s", key)
+		}
+	}
+}
+
+func TestVoipURIHash(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	defer func(uri, keyFile string, key []byte) {
+		*voipURI, *voipURIHashKeyFile, voipHashKey = uri, keyFile, key
+	}(*voipURI, *voipURIHashKeyFile, voipHashKey)
+	*voipURI = voipURIHash
+
+	// The key is required at startup
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := ioutil.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, keyFile := range []string{"", filepath.Join(t.TempDir(), "missing"), empty} {
+		*voipURIHashKeyFile = keyFile
+		if err := LoadVoipHashKey(); err == nil {
+			t.Errorf("hash key file %q accepted", keyFile)
+		}
+	}
+
+	// Without a key, the URI is omitted
+	metrics := scrape(t, newTestExporter(t, server, "voip"))
+	for key := range metrics {
+		if strings.HasPrefix(key, "bbox_voip_line_info") {
+			t.Errorf("URI exported without hash key: %s", key)
+		}
+	}
+
+	hashes := map[string]bool{}
+	for _, secret := range []string{"secret", "other secret"} {
+		*voipURIHashKeyFile = filepath.Join(t.TempDir(), "key")
+		if err := ioutil.WriteFile(*voipURIHashKeyFile, []byte(secret+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := LoadVoipHashKey(); err != nil {
+			t.Fatalf("can't load hash key: %s", err)
+		}
+		metrics := scrape(t, newTestExporter(t, server, "voip"))
+		found := 0
+		for key := range metrics {
+			if !strings.HasPrefix(key, "bbox_voip_line_info") {
+				continue
+			}
+			if strings.Contains(key, "0123456789") || strings.Contains(key, "0601020304") {
+				t.Errorf("phone number exported: %s", key)
+			}
+			hashes[key] = true
+			found++
+		}
+		if found != 1 {
+			t.Errorf("got %d hashed URI, want 1", found)
+		}
+	}
+	if len(hashes) != 2 {
+		t.Errorf("hash doesn't depend on the key: %v", hashes)
+	}
+}
+
+func TestDhcpCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "dhcp"))
+	assertMetric(t, metrics, "bbox_dhcp_enabled{}", 1)
+	assertMetric(t, metrics, "bbox_dhcp_pool_size{}", 10)
+	assertMetric(t, metrics, "bbox_dhcp_leases{}", 1)
+	assertMetric(t, metrics, "bbox_dhcp_pool_utilization_ratio{}", 0.1)
+	assertMetric(t, metrics, "bbox_dhcp_lease_time_seconds{}", 86400)
+	assertMetric(t, metrics, "bbox_dhcp_static_leases{}", 1)
+}
+
+func TestLanSwitchPorts(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "lan"))
+	assertMetric(t, metrics, `bbox_lan_ip_info{ip6state="Up",ip="192.168.1.254",mtu="1500",netmask="255.255.255.0"}`, 1)
+	assertMetric(t, metrics, `bbox_lan_switch_port_up{port="1"}`, 1)
+	assertMetric(t, metrics, `bbox_lan_switch_port_up{port="3"}`, 0)
+	assertMetric(t, metrics, `bbox_lan_switch_port_link_speed{mode="full",port="1"}`, 1000)
+	assertMetric(t, metrics, `bbox_lan_switch_port_link_speed{mode="half",port="2"}`, 100)
+	assertMetric(t, metrics, `bbox_lan_switch_port_flickering{port="2"}`, 4)
+	assertMetric(t, metrics, `bbox_lan_switch_port_blocked{port="4"}`, 1)
+	if _, ok := metrics[`bbox_lan_switch_port_link_speed{mode="",port="3"}`]; ok {
+		t.Errorf("unexpected link speed for a port without link")
+	}
+}
+
+func TestLanMissingIPInformations(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	server.RemoveResponse("/lan/ip")
+
+	metrics := scrape(t, newTestExporter(t, server, "lan"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="lan"}`, 1)
+	if _, ok := metrics[`bbox_lan_switch_port_up{port="1"}`]; ok {
+		t.Errorf("unexpected switch port without IP informations")
+	}
+	if _, ok := metrics["bbox_lan_transmitted_bytes{}"]; !ok {
+		t.Errorf("LAN statistics missing")
+	}
+
+	// The statistics are kept if the IP informations fail
+	server.SetResponse("/lan/ip", []byte(`[{"lan":`))
+	metrics = scrape(t, newTestExporter(t, server, "lan"))
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="lan",reason="decode"}`, 1)
+	if _, ok := metrics["bbox_lan_transmitted_bytes{}"]; !ok {
+		t.Errorf("LAN statistics missing")
+	}
+}
+
+func TestIPTVCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "iptv"))
+	assertMetric(t, metrics, `bbox_iptv_channel{name="TF1",number="1"}`, 1)
+	assertMetric(t, metrics, `bbox_iptv_channel{name="France 2",number="2"}`, 0)
+	assertMetric(t, metrics, "bbox_iptv_diagnostics_up{}", 1)
+	assertMetric(t, metrics, `bbox_iptv_multicast_joined{address="232.0.100.2",number="2"}`, 0)
+	assertMetric(t, metrics, `bbox_iptv_multicast_errors_total{address="232.0.100.2",number="2"}`, 3)
+
+	// A multicast group listed twice is exported once
+	server.SetResponse("/iptv/diags", []byte(`[{"iptv": {"diags": {"status": "Up", "multicast": [
+		{"address": "232.0.100.1", "number": 1, "state": "Joined", "errors": 0},
+		{"address": "232.0.100.1", "number": 1, "state": "Joined", "errors": 0}
+	]}}}]`))
+	metrics = scrape(t, newTestExporter(t, server, "iptv"))
+	assertMetric(t, metrics, `bbox_iptv_multicast_joined{address="232.0.100.1",number="1"}`, 1)
+
+	// Firmwares without diagnostics only export the channels
+	server.RemoveResponse("/iptv/diags")
+	metrics = scrape(t, newTestExporter(t, server, "iptv"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="iptv"}`, 1)
+	assertMetric(t, metrics, `bbox_iptv_channel{name="TF1",number="1"}`, 1)
+}
+
+func TestServicesCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "services"))
+	assertMetric(t, metrics, `bbox_service_enabled{name="dyndns"}`, 0)
+	assertMetric(t, metrics, `bbox_service_enabled{name="usb_dlna"}`, 1)
+	assertMetric(t, metrics, `bbox_service_running{name="firewall"}`, 1)
+	assertMetric(t, metrics, `bbox_service_rules{name="nat"}`, 3)
+	assertMetric(t, metrics, `bbox_service_remote_admin_info{activable="1",duration="",port="8560"}`, 1)
+	if _, ok := metrics[`bbox_service_running{name="notification"}`]; ok {
+		t.Errorf("unexpected running metric for a service without status")
+	}
+}
+
+func TestRulesCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "rules"))
+	assertMetric(t, metrics, `bbox_firewall_rule_info{action="accept",destination="192.168.1.10",destination_ports="22",enabled="1",id="1",ip_version="4",protocol="tcp",source="203.0.113.10"}`, 1)
+	assertMetric(t, metrics, `bbox_firewall_rule_hits_total{id="1",ip_version="4"}`, 42)
+	assertMetric(t, metrics, `bbox_firewall_rule_hits_total{id="1",ip_version="6"}`, 7)
+	if _, ok := metrics[`bbox_firewall_rule_hits_total{id="2",ip_version="4"}`]; ok {
+		t.Errorf("unexpected hits for a rule without counter")
+	}
+	assertMetric(t, metrics, `bbox_nat_rule_info{enabled="1",external_port="8443",id="1",internal_ip="192.168.1.10",internal_port="443",origin="manual",protocol="tcp"}`, 1)
+	assertMetric(t, metrics, `bbox_nat_rule_info{enabled="1",external_port="3074",id="1",internal_ip="192.168.1.21",internal_port="3074",origin="upnp",protocol="udp"}`, 1)
+
+	server.RemoveResponse("/firewall/v6/rules")
+	server.RemoveResponse("/upnp/igd/rules")
+	metrics = scrape(t, newTestExporter(t, server, "rules"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="rules"}`, 1)
+	if _, ok := metrics[`bbox_firewall_rule_hits_total{id="1",ip_version="6"}`]; ok {
+		t.Errorf("unexpected IPv6 rule without the endpoint")
+	}
+}
+
+func TestDeviceLogCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server, "devicelog")
+	metrics := scrape(t, e)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="warning",type="login_remote_failed"}`, 2)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="notice",type="wan_up"}`, 1)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="error",type="wan_down"}`, 1)
+
+	// A new event the same second as the last ones, another one later
+	server.SetResponse("/device/log", []byte(`[{"log": [
+		{"date": "2021-10-17T18:15:00+0200", "log": "WAN_DOWN", "severity": "error", "param": "FTTH"},
+		{"date": "2021-10-17T18:10:08+0200", "log": "LOGIN_REMOTE_FAILED", "severity": "warning", "param": "203.0.113.9"},
+		{"date": "2021-10-17T18:10:08+0200", "log": "LOGIN_REMOTE_FAILED", "severity": "warning", "param": "203.0.113.7"},
+		{"date": "2021-10-17T18:10:08+0200", "log": "LOGIN_REMOTE_FAILED", "severity": "warning", "param": "203.0.113.8"},
+		{"date": "2021-10-17T18:02:41+0200", "log": "WAN_UP", "severity": "notice", "param": "FTTH"}
+	]}]`))
+	// The counts are kept by the client, like for the probes of a target
+	e, err := NewExporter(e.Bbox, []string{"devicelog"}, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("can't create exporter: %s", err)
+	}
+	metrics = scrape(t, e)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="warning",type="login_remote_failed"}`, 3)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="notice",type="wan_up"}`, 1)
+	assertMetric(t, metrics, `bbox_device_log_events_total{severity="error",type="wan_down"}`, 2)
+
+	// The same log, with an entry whose date is invalid, is read once
+	server.SetResponse("/device/log", []byte(`[{"log": [
+		{"date": "", "log": "REBOOT", "severity": "notice", "param": ""},
+		{"date": "2021-10-17T18:15:00+0200", "log": "WAN_DOWN", "severity": "error", "param": "FTTH"},
+		{"date": "2021-10-17T18:10:08+0200", "log": "LOGIN_REMOTE_FAILED", "severity": "warning", "param": "203.0.113.9"}
+	]}]`))
+	for i := 0; i < 2; i++ {
+		metrics = scrape(t, e)
+		assertMetric(t, metrics, `bbox_device_log_events_total{severity="notice",type="reboot"}`, 1)
+		assertMetric(t, metrics, `bbox_device_log_events_total{severity="warning",type="login_remote_failed"}`, 3)
+		assertMetric(t, metrics, `bbox_device_log_events_total{severity="error",type="wan_down"}`, 2)
+	}
+}
+
+func TestUsbCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "usb"))
+	assertMetric(t, metrics, `bbox_usb_printer_info{manufacturer="HP",printer="1",product="DeskJet 3630",state="Ready"}`, 1)
+	assertMetric(t, metrics, `bbox_usb_storage_info{device="1",manufacturer="SanDisk",product="Ultra",state="Mounted"}`, 1)
+	assertMetric(t, metrics, `bbox_usb_storage_partition_info{device="1",fstype="ext4",label="NAS",partition="1",state="Mounted"}`, 1)
+	assertMetric(t, metrics, `bbox_usb_storage_bytes{device="1",partition="1",type="total"}`, 62499160064)
+	assertMetric(t, metrics, `bbox_usb_storage_bytes{device="1",partition="1",type="used"}`, 56249244057)
+	assertMetric(t, metrics, `bbox_usb_storage_bytes{device="1",partition="1",type="free"}`, 6249916007)
+	assertMetric(t, metrics, `bbox_usb_storage_bytes{device="1",partition="2",type="free"}`, 805306368)
+
+	// An empty free space is computed, like a missing one
+	storage := strings.Replace(string(bboxtest.Fixture("usb_storage.json")), `"free": 6249916007`, `"free": ""`, 1)
+	server.SetResponse("/usb/storage", []byte(storage))
+	metrics = scrape(t, newTestExporter(t, server, "usb"))
+	assertMetric(t, metrics, `bbox_usb_storage_bytes{device="1",partition="1",type="free"}`, 6249916007)
+
+	server.RemoveResponse("/usb")
+	server.RemoveResponse("/usb/storage")
+	metrics = scrape(t, newTestExporter(t, server, "usb"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="usb"}`, 1)
+}
+
+func TestWanIPInformations(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server, "wan")
+	metrics := scrape(t, e)
+	assertMetric(t, metrics, `bbox_wan_internet_state{}`, 2)
+	assertMetric(t, metrics, `bbox_wan_link_up{type="ftth"}`, 1)
+	assertMetric(t, metrics, `bbox_wan_mtu{}`, 1500)
+	assertMetric(t, metrics, `bbox_wan_ip_info{address="203.0.113.42",dnsservers="192.0.2.53,192.0.2.54",gateway="203.0.113.1",ip6prefix="2001:db8:1::/56",ip6state="Up"}`, 1)
+	assertMetric(t, metrics, `bbox_wan_ip_changes_total{}`, 0)
+
+	// The WAN goes down, then comes back with another address
+	wanIP := string(bboxtest.Fixture("wan_ip.json"))
+	server.SetResponse("/wan/ip", []byte(strings.Replace(wanIP, `"address": "203.0.113.42"`, `"address": ""`, 1)))
+	metrics = scrape(t, e)
+	assertMetric(t, metrics, `bbox_wan_ip_changes_total{}`, 0)
+	server.SetResponse("/wan/ip", []byte(strings.Replace(wanIP, `"address": "203.0.113.42"`, `"address": "198.51.100.7"`, 1)))
+	metrics = scrape(t, e)
+	assertMetric(t, metrics, `bbox_wan_ip_changes_total{}`, 1)
+	metrics = scrape(t, e)
+	assertMetric(t, metrics, `bbox_wan_ip_changes_total{}`, 1)
+
+	// Statistics and diagnostics may be empty while the WAN is down
+	server.SetResponse("/wan/ip/stats", []byte(`[]`))
+	server.SetResponse("/wan/diags", []byte(`[]`))
+	metrics = scrape(t, e)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="wan"}`, 1)
+	assertMetric(t, metrics, `bbox_wan_mtu{}`, 1500)
+	if _, ok := metrics["bbox_wan_received_bytes{}"]; ok {
+		t.Errorf("unexpected statistics metric")
+	}
+}
+
+type faultyCollector struct {
+	panics bool
+}
+
+func (c faultyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- up
+}
+
+func (c faultyCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	if c.panics {
+		var metrics []bbox.DNSMetrics
+		_ = metrics[0]
+	}
+	storeMetric(ch, 1, dnsMin)
+	storeMetric(ch, 1, dnsMin)
+	return nil
+}
+
+func TestExporterFaultyCollectors(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	e := newTestExporter(t, server, "device")
+	e.collectors["panic"] = faultyCollector{panics: true}
+	e.collectors["duplicate"] = faultyCollector{}
+	metrics := scrape(t, e)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="panic"}`, 0)
+	assertMetric(t, metrics, `bbox_scrape_collector_error{collector="panic",reason="panic"}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="duplicate"}`, 1)
+	assertMetric(t, metrics, `bbox_dns_min{}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="device"}`, 1)
+	assertMetric(t, metrics, `bbox_up{}`, 1)
+}
+
+func TestExporterEmptyResponses(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	for _, endpoint := range []string{"/device/cpu", "/device/mem", "/dns/stats"} {
+		server.SetResponse(endpoint, []byte(`[]`))
+	}
+
+	metrics := scrape(t, newTestExporter(t, server, "device", "dns"))
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="device"}`, 1)
+	assertMetric(t, metrics, `bbox_scrape_collector_success{collector="dns"}`, 1)
+	assertMetric(t, metrics, `bbox_device_temperature{}`, 52)
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	ftthState = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_state"),
+		"LinkState of the GEth FTTH port",
+		nil, nil,
+	)
+	ftthInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_info"),
+		"Mode of the FTTH link",
+		[]string{"mode"}, nil,
+	)
+	ftthOntState = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_ont_state"),
+		"GPON activation state of the ONT, from 1 to 7 (5 is operational)",
+		nil, nil,
+	)
+	ftthRxPower = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_sfp_rx_power_dbm"),
+		"Optical power received by the SFP in dBm",
+		nil, nil,
+	)
+	ftthTxPower = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_sfp_tx_power_dbm"),
+		"Optical power transmitted by the SFP in dBm",
+		nil, nil,
+	)
+	ftthTemperature = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_sfp_temperature_celsius"),
+		"Temperature of the SFP",
+		nil, nil,
+	)
+	ftthVoltage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_sfp_voltage_volts"),
+		"Supply voltage of the SFP",
+		nil, nil,
+	)
+	ftthBias = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ftth_sfp_bias_milliamperes"),
+		"Laser bias current of the SFP",
+		nil, nil,
+	)
+)
+
+func init() {
+	registerCollector("ftth", defaultEnabled, NewFtthCollector)
+}
+
+type ftthCollector struct {
+	logger log.Logger
+}
+
+// NewFtthCollector returns a new Collector exposing FTTH optical link metrics.
+// It only queries a Bbox using FTTH.
+func NewFtthCollector(logger log.Logger) Collector {
+	return &ftthCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *ftthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ftthState
+	ch <- ftthInfo
+	ch <- ftthOntState
+	ch <- ftthRxPower
+	ch <- ftthTxPower
+	ch <- ftthTemperature
+	ch <- ftthVoltage
+	ch <- ftthBias
+}
+
+// Update implements the Collector interface.
+func (c *ftthCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	links, err := client.GetWanLinks(ctx)
+	if err != nil {
+		return err
+	}
+	if !links.FTTH {
+		level.Debug(c.logger).Log("msg", "Bbox doesn't use FTTH")
+		return nil
+	}
+	metrics, err := client.GetFtthMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	if len(metrics) == 0 {
+		level.Warn(c.logger).Log("msg", "No metrics for FTTH")
+		return nil
+	}
+	storeFtthMetrics(ch, metrics[0])
+	return nil
+}
+
+func storeFtthMetrics(ch chan<- prometheus.Metric, metrics bbox.FtthStatistics) {
+	ftth := metrics.Wan.Ftth
+	state := 0.0
+	if strings.ToUpper(ftth.State) == "UP" {
+		state = 1.0
+	}
+	storeMetric(ch, state, ftthState)
+	storeMetric(ch, 1.0, ftthInfo, ftth.Mode)
+	// The ONT state is sent as O1 to O7
+	if ontState, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(ftth.OntState), "O")); err == nil {
+		storeMetric(ch, float64(ontState), ftthOntState)
+	}
+	if value, ok := ftth.SFP.RxPower.Value(); ok {
+		storeMetric(ch, value, ftthRxPower)
+	}
+	if value, ok := ftth.SFP.TxPower.Value(); ok {
+		storeMetric(ch, value, ftthTxPower)
+	}
+	if value, ok := ftth.SFP.Temperature.Value(); ok {
+		storeMetric(ch, value, ftthTemperature)
+	}
+	if value, ok := ftth.SFP.Voltage.Value(); ok {
+		storeMetric(ch, value, ftthVoltage)
+	}
+	if value, ok := ftth.SFP.Bias.Value(); ok {
+		storeMetric(ch, value, ftthBias)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	hostMacInclude = kingpin.Flag(
+		"collector.hosts.mac-include",
+		"Regexp of the MAC addresses of the hosts to export (default: all).",
+	).Regexp()
+	hostMacExclude = kingpin.Flag(
+		"collector.hosts.mac-exclude",
+		"Regexp of the MAC addresses of the hosts to ignore.",
+	).Regexp()
+)
+
+var (
+	hostInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_info"),
+		"Informations of a device known by the Bbox",
+		[]string{"mac", "hostname", "ip", "link", "devicetype"}, nil,
+	)
+	hostActive = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_active"),
+		"Is the device connected",
+		[]string{"mac"}, nil,
+	)
+	hostWirelessRssi = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_wireless_rssi_dbm"),
+		"Signal strength of the WIFI device in dBm",
+		[]string{"mac", "antenna"}, nil,
+	)
+	hostWirelessRate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_wireless_rate"),
+		"WIFI link rate of the device in Mbps",
+		[]string{"mac"}, nil,
+	)
+	hostEthernetSpeed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_ethernet_speed_mbps"),
+		"Ethernet link speed of the device in Mbps",
+		[]string{"mac"}, nil,
+	)
+	hostPingAverage = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_ping_average_ms"),
+		"Average ping of the device in milliseconds",
+		[]string{"mac"}, nil,
+	)
+	hostLease = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_host_lease_seconds"),
+		"Remaining time of the DHCP lease of the device",
+		[]string{"mac"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("hosts", defaultDisabled, NewHostsCollector)
+}
+
+type hostsCollector struct {
+	logger  log.Logger
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// NewHostsCollector returns a new Collector exposing a metric per device of the LAN.
+func NewHostsCollector(logger log.Logger) Collector {
+	return &hostsCollector{
+		logger:  logger,
+		include: *hostMacInclude,
+		exclude: *hostMacExclude,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *hostsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostInfo
+	ch <- hostActive
+	ch <- hostWirelessRssi
+	ch <- hostWirelessRate
+	ch <- hostEthernetSpeed
+	ch <- hostPingAverage
+	ch <- hostLease
+}
+
+// Update implements the Collector interface.
+func (c *hostsCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	hosts, err := client.GetLanHosts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		mac := strings.ToLower(host.Macaddress)
+		if mac == "" || !c.matches(mac) {
+			continue
+		}
+		storeHostMetrics(ch, mac, host)
+	}
+	level.Debug(c.logger).Log("msg", "LAN hosts", "count", len(hosts))
+	return nil
+}
+
+// matches checks the MAC address against the include and exclude filters.
+func (c *hostsCollector) matches(mac string) bool {
+	if c.include != nil && !c.include.MatchString(mac) {
+		return false
+	}
+	return c.exclude == nil || !c.exclude.MatchString(mac)
+}
+
+func storeHostMetrics(ch chan<- prometheus.Metric, mac string, host bbox.LanHost) {
+	storeMetric(ch, 1.0, hostInfo, mac, host.Hostname, host.Ipaddress, host.Link, host.Devicetype)
+	storeMetric(ch, float64(host.Active), hostActive, mac)
+	storeMetric(ch, float64(host.Ping.Average), hostPingAverage, mac)
+	if host.Lease > 0 {
+		storeMetric(ch, float64(host.Lease), hostLease, mac)
+	}
+	if host.Ethernet.Speed > 0 {
+		storeMetric(ch, float64(host.Ethernet.Speed), hostEthernetSpeed, mac)
+	}
+	for antenna, rssi := range []float64{float64(host.Wireless.Rssi0), float64(host.Wireless.Rssi1), float64(host.Wireless.Rssi2)} {
+		// An unused antenna has no signal
+		if rssi != 0 {
+			storeMetric(ch, rssi, hostWirelessRssi, mac, strconv.Itoa(antenna))
+		}
+	}
+	if host.Wireless.Rate > 0 {
+		storeMetric(ch, float64(host.Wireless.Rate), hostWirelessRate, mac)
+	}
+}
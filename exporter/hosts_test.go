@@ -0,0 +1,69 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+)
+
+func TestHostsCollector(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+
+	metrics := scrape(t, newTestExporter(t, server, "hosts"))
+	assertMetric(t, metrics, `bbox_lan_host_info{devicetype="Smartphone",hostname="phone",ip="192.168.1.21",link="Wifi 5",mac="a4:83:e7:11:22:33"}`, 1)
+	assertMetric(t, metrics, `bbox_lan_host_wireless_rssi_dbm{antenna="0",mac="a4:83:e7:11:22:33"}`, -61)
+	assertMetric(t, metrics, `bbox_lan_host_wireless_rssi_dbm{antenna="1",mac="3c:22:fb:44:55:66"}`, -80)
+	assertMetric(t, metrics, `bbox_lan_host_wireless_rate{mac="a4:83:e7:11:22:33"}`, 866)
+	assertMetric(t, metrics, `bbox_lan_host_ethernet_speed_mbps{mac="00:11:32:aa:bb:cc"}`, 1000)
+	assertMetric(t, metrics, `bbox_lan_host_lease_seconds{mac="a4:83:e7:11:22:33"}`, 82311)
+	assertMetric(t, metrics, `bbox_lan_host_active{mac="3c:22:fb:44:55:66"}`, 0)
+	if _, ok := metrics[`bbox_lan_host_wireless_rssi_dbm{antenna="2",mac="a4:83:e7:11:22:33"}`]; ok {
+		t.Errorf("unexpected metric for an unused antenna")
+	}
+}
+
+func TestHostsCollectorFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		mac     string
+		want    bool
+	}{
+		{name: "no filter", mac: "00:11:32:aa:bb:cc", want: true},
+		{name: "included", include: "^00:11:32:", mac: "00:11:32:aa:bb:cc", want: true},
+		{name: "not included", include: "^00:11:32:", mac: "a4:83:e7:11:22:33", want: false},
+		{name: "excluded", exclude: "^a4:83:e7:", mac: "a4:83:e7:11:22:33", want: false},
+		{name: "included and excluded", include: ":33$", exclude: "^a4:", mac: "a4:83:e7:11:22:33", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &hostsCollector{}
+			if tt.include != "" {
+				c.include = regexp.MustCompile(tt.include)
+			}
+			if tt.exclude != "" {
+				c.exclude = regexp.MustCompile(tt.exclude)
+			}
+			if got := c.matches(tt.mac); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.mac, got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,11 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
@@ -23,15 +28,100 @@ import (
 var (
 	iptvChannel = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "iptv_channel"),
-		"Name of channel",
-		[]string{"name"}, nil,
+		"Is the channel received",
+		[]string{"number", "name"}, nil,
+	)
+	iptvDiagnosticsUp = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "iptv_diagnostics_up"),
+		"Is the IP TV service up",
+		nil, nil,
+	)
+	iptvMulticastJoined = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "iptv_multicast_joined"),
+		"Is the multicast group of the channel joined",
+		[]string{"number", "address"}, nil,
+	)
+	iptvMulticastErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "iptv_multicast_errors_total"),
+		"Number of reception errors on the multicast group",
+		[]string{"number", "address"}, nil,
 	)
 )
 
+func init() {
+	registerCollector("iptv", defaultEnabled, NewIPTVCollector)
+}
+
+type iptvCollector struct {
+	logger log.Logger
+}
+
+// NewIPTVCollector returns a new Collector exposing iptv metrics.
+func NewIPTVCollector(logger log.Logger) Collector {
+	return &iptvCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *iptvCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeIPTVMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *iptvCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetIPTVMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeIPTVMetrics(ch, *metrics)
+	return nil
+}
+
 func describeIPTVMetrics(ch chan<- *prometheus.Desc) {
 	ch <- iptvChannel
+	ch <- iptvDiagnosticsUp
+	ch <- iptvMulticastJoined
+	ch <- iptvMulticastErrors
 }
 
 func storeIPTVMetrics(ch chan<- prometheus.Metric, metrics bbox.IPTVMetrics) {
-	// storeMetric(ch, float64(metrics.Informations[0].IPTV[0].Receipt), iptvChannel, metrics.Informations[0].IPTV[0].Name)
+	if len(metrics.Informations) > 0 {
+		// A channel watched by several receivers is listed once per receiver
+		received := map[[2]string]float64{}
+		for _, channel := range metrics.Informations[0].IPTV {
+			key := [2]string{strconv.Itoa(int(channel.Number)), channel.Name}
+			if float64(channel.Receipt) >= received[key] {
+				received[key] = float64(channel.Receipt)
+			}
+		}
+		for key, receipt := range received {
+			storeMetric(ch, receipt, iptvChannel, key[0], key[1])
+		}
+	}
+	if len(metrics.Diagnostics) > 0 {
+		diags := metrics.Diagnostics[0].IPTV.Diags
+		up := 0.0
+		if strings.EqualFold(diags.Status, "Up") {
+			up = 1.0
+		}
+		storeMetric(ch, up, iptvDiagnosticsUp)
+		// Some firmwares list a multicast group several times
+		groups := map[[2]string]bool{}
+		for _, group := range diags.Multicast {
+			number := strconv.Itoa(int(group.Number))
+			key := [2]string{number, group.Address}
+			if groups[key] {
+				continue
+			}
+			groups[key] = true
+			joined := 0.0
+			if strings.EqualFold(group.State, "Joined") {
+				joined = 1.0
+			}
+			storeMetric(ch, joined, iptvMulticastJoined, number, group.Address)
+			ch <- prometheus.MustNewConstMetric(
+				iptvMulticastErrors, prometheus.CounterValue, float64(group.Errors), number, group.Address)
+		}
+	}
 }
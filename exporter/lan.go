@@ -15,6 +15,10 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
+	"strings"
+
 	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -23,6 +27,32 @@ import (
 )
 
 var (
+	lanIPInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_ip_info"),
+		"IP configuration of the LAN",
+		[]string{"ip", "netmask", "mtu", "ip6state"}, nil,
+	)
+	switchPortUp = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_switch_port_up"),
+		"Is an Ethernet device connected to the port",
+		[]string{"port"}, nil,
+	)
+	switchPortLinkSpeed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_switch_port_link_speed"),
+		"Link speed of the port in Mbps",
+		[]string{"port", "mode"}, nil,
+	)
+	switchPortBlocked = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_switch_port_blocked"),
+		"Is the port blocked",
+		[]string{"port"}, nil,
+	)
+	switchPortFlickering = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "lan_switch_port_flickering"),
+		"Number of link flaps of the port",
+		[]string{"port"}, nil,
+	)
+
 	hosts = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "lan_connected_devices"),
 		"Number of devices connected",
@@ -72,7 +102,41 @@ var (
 	)
 )
 
+func init() {
+	registerCollector("lan", defaultEnabled, NewLanCollector)
+}
+
+type lanCollector struct {
+	logger log.Logger
+}
+
+// NewLanCollector returns a new Collector exposing lan metrics.
+func NewLanCollector(logger log.Logger) Collector {
+	return &lanCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *lanCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeLanMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *lanCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetLanMetrics(ctx)
+	if metrics != nil {
+		storeLanMetrics(c.logger, ch, *metrics)
+	}
+	return err
+}
+
 func describeLanMetrics(ch chan<- *prometheus.Desc) {
+	ch <- lanIPInfo
+	ch <- switchPortUp
+	ch <- switchPortLinkSpeed
+	ch <- switchPortBlocked
+	ch <- switchPortFlickering
 	ch <- hosts
 	ch <- txBytesLan
 	ch <- txPacketsLan
@@ -85,6 +149,11 @@ func describeLanMetrics(ch chan<- *prometheus.Desc) {
 }
 
 func storeLanMetrics(logger log.Logger, ch chan<- prometheus.Metric, metrics bbox.LanMetrics) {
+	if len(metrics.IPInformations) > 0 {
+		storeLanIPMetrics(ch, metrics.IPInformations[0])
+	} else {
+		level.Warn(logger).Log("msg", "No IP informations for LAN")
+	}
 	// storeMetric(ch, float64(len(metrics.Devices[0].Hosts.List)), hosts)
 	lanHosts := map[string]int{}
 	if len(metrics.Devices) > 0 {
@@ -114,3 +183,38 @@ func storeLanMetrics(logger log.Logger, ch chan<- prometheus.Metric, metrics bbo
 		level.Warn(logger).Log("msg", "No metrics statistics for LAN")
 	}
 }
+
+func storeLanIPMetrics(ch chan<- prometheus.Metric, informations bbox.LanIPInformations) {
+	ip := informations.Lan.IP
+	storeMetric(ch, 1.0, lanIPInfo, ip.Ipaddress, ip.Netmask, strconv.Itoa(int(ip.Mtu)), ip.IP6State)
+	for _, port := range informations.Lan.Switch.Ports {
+		id := strconv.Itoa(int(port.ID))
+		up := 0.0
+		if strings.EqualFold(port.State, "Up") {
+			up = 1.0
+		}
+		storeMetric(ch, up, switchPortUp, id)
+		storeMetric(ch, float64(port.Blocked), switchPortBlocked, id)
+		storeMetric(ch, float64(port.Flickering), switchPortFlickering, id)
+		if speed, mode, ok := parseLinkMode(port.LinkMode); ok {
+			storeMetric(ch, speed, switchPortLinkSpeed, id, mode)
+		}
+	}
+}
+
+// parseLinkMode splits the link mode of a port, e.g. "1000Full", into its speed
+// in Mbps and its duplex mode.
+func parseLinkMode(linkMode string) (float64, string, bool) {
+	i := strings.IndexFunc(linkMode, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(linkMode)
+	}
+	if i == 0 {
+		return 0, "", false
+	}
+	speed, err := strconv.ParseFloat(linkMode[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return speed, strings.ToLower(linkMode[i:]), true
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	firewallRuleInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "firewall_rule_info"),
+		"Informations of a firewall rule",
+		[]string{"id", "ip_version", "action", "protocol", "source", "destination", "destination_ports", "enabled"}, nil,
+	)
+	firewallRuleHits = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "firewall_rule_hits_total"),
+		"Number of packets matching the firewall rule",
+		[]string{"id", "ip_version"}, nil,
+	)
+	natRuleInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "nat_rule_info"),
+		"Informations of a port forwarding, configured manually or by UPnP",
+		[]string{"id", "origin", "protocol", "external_port", "internal_ip", "internal_port", "enabled"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("rules", defaultDisabled, NewRulesCollector)
+}
+
+type rulesCollector struct {
+	logger log.Logger
+}
+
+// NewRulesCollector returns a new Collector exposing firewall and port forwarding rules.
+func NewRulesCollector(logger log.Logger) Collector {
+	return &rulesCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *rulesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- firewallRuleInfo
+	ch <- firewallRuleHits
+	ch <- natRuleInfo
+}
+
+// Update implements the Collector interface.
+func (c *rulesCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetRulesMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeFirewallRules(ch, metrics.FirewallRules, "4")
+	storeFirewallRules(ch, metrics.FirewallV6Rules, "6")
+	storeNatRules(ch, metrics.NatRules, "manual")
+	storeNatRules(ch, metrics.UpnpRules, "upnp")
+	return nil
+}
+
+func storeFirewallRules(ch chan<- prometheus.Metric, rules []bbox.FirewallRule, ipVersion string) {
+	for _, rule := range rules {
+		id := strconv.Itoa(int(rule.ID))
+		storeMetric(ch, 1.0, firewallRuleInfo, id, ipVersion,
+			strings.ToLower(rule.Action),
+			strings.ToLower(rule.Protocols),
+			rule.SrcIP,
+			rule.DstIP,
+			rule.DstPorts,
+			strconv.Itoa(int(rule.Enable)))
+		if rule.Utilisation != nil {
+			ch <- prometheus.MustNewConstMetric(
+				firewallRuleHits, prometheus.CounterValue, float64(*rule.Utilisation), id, ipVersion)
+		}
+	}
+}
+
+func storeNatRules(ch chan<- prometheus.Metric, rules []bbox.NatRule, origin string) {
+	for _, rule := range rules {
+		storeMetric(ch, 1.0, natRuleInfo,
+			strconv.Itoa(int(rule.ID)),
+			origin,
+			strings.ToLower(rule.Protocol),
+			rule.ExternalPort,
+			rule.InternalIP,
+			rule.InternalPort,
+			strconv.Itoa(int(rule.Enable)))
+	}
+}
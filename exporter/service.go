@@ -14,6 +14,10 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
@@ -22,31 +26,109 @@ import (
 var (
 	serviceUp = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "service_status"),
-		"BBox services status",
+		"BBox services status (deprecated, use bbox_service_enabled)",
+		[]string{"name"}, nil,
+	)
+	serviceEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "service_enabled"),
+		"Is the BBox service enabled",
 		[]string{"name"}, nil,
 	)
+	serviceRunning = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "service_running"),
+		"Is the BBox service running",
+		[]string{"name"}, nil,
+	)
+	serviceRules = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "service_rules"),
+		"Number of rules of the BBox service",
+		[]string{"name"}, nil,
+	)
+	serviceRemoteAdminInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "service_remote_admin_info"),
+		"Settings of the remote administration",
+		[]string{"port", "duration", "activable"}, nil,
+	)
 )
 
+func init() {
+	registerCollector("services", defaultEnabled, NewServicesCollector)
+}
+
+type servicesCollector struct {
+	logger log.Logger
+}
+
+// NewServicesCollector returns a new Collector exposing services metrics.
+func NewServicesCollector(logger log.Logger) Collector {
+	return &servicesCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *servicesCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeServicesMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *servicesCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetServicesMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeServicesMetrics(ch, *metrics)
+	return nil
+}
+
 func describeServicesMetrics(ch chan<- *prometheus.Desc) {
 	ch <- serviceUp
+	ch <- serviceEnabled
+	ch <- serviceRunning
+	ch <- serviceRules
+	ch <- serviceRemoteAdminInfo
 }
 
 func storeServicesMetrics(ch chan<- prometheus.Metric, metrics bbox.ServicesMetrics) {
-	storeMetric(ch, float64(metrics.Informations[0].Services.Firewall.Enable), serviceUp, "firewall")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Dyndns.Enable), serviceUp, "dydns")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Dhcp.Enable), serviceUp, "dhcp")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Nat.Enable), serviceUp, "nat")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Gamermode.Enable), serviceUp, "gamermode")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Upnp.Igd.Enable), serviceUp, "upnp")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Remote.Proxywol.Enable), serviceUp, "remote_proxywol")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Remote.Admin.Enable), serviceUp, "remote_admin")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Parentalcontrol.Enable), serviceUp, "parentalcontrol")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Wifischeduler.Enable), serviceUp, "wifischeduler")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Voipscheduler.Enable), serviceUp, "voipscheduler")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Notification.Enable), serviceUp, "notification")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Hotspot.Enable), serviceUp, "hotspot")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Usb.Samba.Enable), serviceUp, "usb_samba")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Usb.Printer.Enable), serviceUp, "usb_printer")
-	storeMetric(ch, float64(metrics.Informations[0].Services.Usb.Dlna.Enable), serviceUp, "user_dlna")
+	if len(metrics.Informations) == 0 {
+		return
+	}
+	services := metrics.Informations[0].Services
+	storeServiceMetrics(ch, "firewall", int(services.Firewall.Enable), int(services.Firewall.Status))
+	storeServiceMetrics(ch, "dyndns", int(services.Dyndns.Enable), int(services.Dyndns.State))
+	storeServiceMetrics(ch, "dhcp", int(services.Dhcp.Enable), int(services.Dhcp.Status))
+	storeServiceMetrics(ch, "nat", int(services.Nat.Enable), int(services.Nat.Status))
+	storeServiceMetrics(ch, "gamermode", int(services.Gamermode.Enable), int(services.Gamermode.Status))
+	storeServiceMetrics(ch, "upnp", int(services.Upnp.Igd.Enable), int(services.Upnp.Igd.Status))
+	storeServiceMetrics(ch, "remote_proxywol", int(services.Remote.Proxywol.Enable), int(services.Remote.Proxywol.Status))
+	storeServiceMetrics(ch, "remote_admin", int(services.Remote.Admin.Enable), int(services.Remote.Admin.Status))
+	storeServiceMetrics(ch, "hotspot", int(services.Hotspot.Enable), int(services.Hotspot.Status))
+	storeServiceMetrics(ch, "usb_samba", int(services.Usb.Samba.Enable), int(services.Usb.Samba.Status))
+	storeServiceMetrics(ch, "usb_printer", int(services.Usb.Printer.Enable), int(services.Usb.Printer.Status))
+	storeServiceMetrics(ch, "usb_dlna", int(services.Usb.Dlna.Enable), int(services.Usb.Dlna.Status))
+
+	// These services have no status
+	storeMetric(ch, float64(services.Parentalcontrol.Enable), serviceUp, "parentalcontrol")
+	storeMetric(ch, float64(services.Parentalcontrol.Enable), serviceEnabled, "parentalcontrol")
+	storeMetric(ch, float64(services.Wifischeduler.Enable), serviceUp, "wifischeduler")
+	storeMetric(ch, float64(services.Wifischeduler.Enable), serviceEnabled, "wifischeduler")
+	storeMetric(ch, float64(services.Voipscheduler.Enable), serviceUp, "voipscheduler")
+	storeMetric(ch, float64(services.Voipscheduler.Enable), serviceEnabled, "voipscheduler")
+	storeMetric(ch, float64(services.Notification.Enable), serviceUp, "notification")
+	storeMetric(ch, float64(services.Notification.Enable), serviceEnabled, "notification")
+
+	storeMetric(ch, float64(services.Firewall.Nbrules), serviceRules, "firewall")
+	storeMetric(ch, float64(services.Dyndns.Nbrules), serviceRules, "dyndns")
+	storeMetric(ch, float64(services.Dhcp.Nbrules), serviceRules, "dhcp")
+	storeMetric(ch, float64(services.Nat.Nbrules), serviceRules, "nat")
+	storeMetric(ch, float64(services.Upnp.Igd.Nbrules), serviceRules, "upnp")
+
+	admin := services.Remote.Admin
+	storeMetric(ch, 1.0, serviceRemoteAdminInfo, strconv.Itoa(int(admin.Port)), admin.Duration, strconv.Itoa(int(admin.Activable)))
+}
 
+func storeServiceMetrics(ch chan<- prometheus.Metric, name string, enable int, status int) {
+	storeMetric(ch, float64(enable), serviceUp, name)
+	storeMetric(ch, float64(enable), serviceEnabled, name)
+	storeMetric(ch, float64(status), serviceRunning, name)
 }
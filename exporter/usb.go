@@ -0,0 +1,103 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	usbPrinterInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "usb_printer_info"),
+		"Informations of a printer plugged on the Bbox",
+		[]string{"printer", "manufacturer", "product", "state"}, nil,
+	)
+	usbStorageInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "usb_storage_info"),
+		"Informations of a storage device plugged on the Bbox",
+		[]string{"device", "manufacturer", "product", "state"}, nil,
+	)
+	usbStoragePartitionInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "usb_storage_partition_info"),
+		"Informations of a partition of a storage device",
+		[]string{"device", "partition", "label", "fstype", "state"}, nil,
+	)
+	usbStorageBytes = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "usb_storage_bytes"),
+		"Total, used and free space of a partition in bytes",
+		[]string{"device", "partition", "type"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("usb", defaultEnabled, NewUsbCollector)
+}
+
+type usbCollector struct {
+	logger log.Logger
+}
+
+// NewUsbCollector returns a new Collector exposing USB printers and storage metrics.
+func NewUsbCollector(logger log.Logger) Collector {
+	return &usbCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *usbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usbPrinterInfo
+	ch <- usbStorageInfo
+	ch <- usbStoragePartitionInfo
+	ch <- usbStorageBytes
+}
+
+// Update implements the Collector interface.
+func (c *usbCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetUsbMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeUsbMetrics(ch, *metrics)
+	return nil
+}
+
+func storeUsbMetrics(ch chan<- prometheus.Metric, metrics bbox.UsbMetrics) {
+	for _, printer := range metrics.Printers {
+		storeMetric(ch, 1.0, usbPrinterInfo, strconv.Itoa(int(printer.ID)), printer.Manufacturer, printer.Product, printer.State)
+	}
+	for _, storage := range metrics.Storages {
+		device := strconv.Itoa(int(storage.ID))
+		storeMetric(ch, 1.0, usbStorageInfo, device, storage.Manufacturer, storage.Product, storage.State)
+		for _, partition := range storage.Partitions {
+			id := strconv.Itoa(int(partition.ID))
+			storeMetric(ch, 1.0, usbStoragePartitionInfo, device, id, partition.Label, partition.FsType, partition.State)
+			storeMetric(ch, float64(partition.Total), usbStorageBytes, device, id, "total")
+			storeMetric(ch, float64(partition.Used), usbStorageBytes, device, id, "used")
+			// Older firmwares don't report the free space
+			free := float64(partition.Total) - float64(partition.Used)
+			if value, ok := partition.Free.Value(); ok {
+				free = value
+			}
+			storeMetric(ch, free, usbStorageBytes, device, id, "free")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+const (
+	voipURIOmit  = "omit"
+	voipURIHash  = "hash"
+	voipURIClear = "clear"
+)
+
+var (
+	voipURI = kingpin.Flag(
+		"collector.voip.uri",
+		"How the URI of the phone lines, holding the phone number, is exported: omit, hash or clear.",
+	).Default(voipURIOmit).Enum(voipURIOmit, voipURIHash, voipURIClear)
+	voipURIHashKeyFile = kingpin.Flag(
+		"collector.voip.uri-hash-key-file",
+		"File holding the secret key of the hash of the URI of the phone lines.",
+	).Default("").String()
+)
+
+var (
+	voipLineRegistered = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "voip_line_registered"),
+		"Is the phone line registered on the SIP server",
+		[]string{"line"}, nil,
+	)
+	voipLineInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "voip_line_info"),
+		"URI of the phone line",
+		[]string{"line", "uri"}, nil,
+	)
+	voipVoicemailMessages = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "voip_voicemail_messages"),
+		"Number of messages on the voicemail",
+		[]string{"line"}, nil,
+	)
+	voipCalls = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "voip_calls"),
+		"Number of calls in the call log",
+		[]string{"line", "direction", "status"}, nil,
+	)
+	voipCallsDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "voip_calls_duration_seconds"),
+		"Duration of the calls in the call log",
+		[]string{"line", "direction"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("voip", defaultEnabled, NewVoipCollector)
+}
+
+// voipHashKey is the secret key of the hash of the URI, read at startup.
+var voipHashKey []byte
+
+// LoadVoipHashKey reads the secret key of the hash of the URI of the phone
+// lines, if they are hashed. It is called once the flags are parsed.
+func LoadVoipHashKey() error {
+	if *voipURI != voipURIHash {
+		return nil
+	}
+	key, err := readHashKey(*voipURIHashKeyFile)
+	if err != nil {
+		return err
+	}
+	voipHashKey = key
+	return nil
+}
+
+type voipCollector struct {
+	logger  log.Logger
+	uri     string
+	hashKey []byte
+}
+
+// NewVoipCollector returns a new Collector exposing phone lines metrics.
+// Without the key of the hash, the URI is omitted.
+func NewVoipCollector(logger log.Logger) Collector {
+	c := &voipCollector{
+		logger:  logger,
+		uri:     *voipURI,
+		hashKey: voipHashKey,
+	}
+	if c.uri == voipURIHash && len(c.hashKey) == 0 {
+		c.uri = voipURIOmit
+	}
+	return c
+}
+
+// readHashKey reads the secret key of the hash of the URI, as a plain hash of
+// a phone number is easily reversed.
+func readHashKey(filename string) ([]byte, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("--collector.voip.uri-hash-key-file is required to hash the URI")
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read hash key file: %s", err)
+	}
+	key := strings.TrimSpace(string(content))
+	if key == "" {
+		return nil, fmt.Errorf("empty hash key file: %s", filename)
+	}
+	return []byte(key), nil
+}
+
+// Describe implements the Collector interface.
+func (c *voipCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- voipLineRegistered
+	ch <- voipLineInfo
+	ch <- voipVoicemailMessages
+	ch <- voipCalls
+	ch <- voipCallsDuration
+}
+
+// Update implements the Collector interface.
+func (c *voipCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetVoipMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, line := range metrics.Lines {
+		c.storeLineMetrics(ch, line, metrics.CallLogs[int(line.ID)])
+	}
+	return nil
+}
+
+func (c *voipCollector) storeLineMetrics(ch chan<- prometheus.Metric, line bbox.VoipLine, calls []bbox.VoipCall) {
+	id := strconv.Itoa(int(line.ID))
+	registered := 0.0
+	if line.Status == "Up" {
+		registered = 1.0
+	}
+	storeMetric(ch, registered, voipLineRegistered, id)
+	if uri := c.exportedURI(line.URI); uri != "" {
+		storeMetric(ch, 1.0, voipLineInfo, id, uri)
+	}
+	storeMetric(ch, float64(line.MessageCount), voipVoicemailMessages, id)
+
+	// All the series are exported, so they don't disappear with an empty call log
+	counts := map[string]map[string]int{
+		"in":  {"answered": 0, "missed": 0},
+		"out": {"answered": 0, "missed": 0},
+	}
+	durations := map[string]int{"in": 0, "out": 0}
+	for _, call := range calls {
+		if _, ok := counts[call.Type]; !ok {
+			continue
+		}
+		status := "missed"
+		if call.Answered != 0 {
+			status = "answered"
+		}
+		counts[call.Type][status]++
+		durations[call.Type] += int(call.Duration)
+	}
+	for direction, statuses := range counts {
+		for status, count := range statuses {
+			storeMetric(ch, float64(count), voipCalls, id, direction, status)
+		}
+		storeMetric(ch, float64(durations[direction]), voipCallsDuration, id, direction)
+	}
+}
+
+// exportedURI hides the phone number of the URI, unless configured otherwise.
+func (c *voipCollector) exportedURI(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	switch c.uri {
+	case voipURIClear:
+		return uri
+	case voipURIHash:
+		mac := hmac.New(sha256.New, c.hashKey)
+		mac.Write([]byte(uri))
+		return hex.EncodeToString(mac.Sum(nil)[:8])
+	default:
+		return ""
+	}
+}
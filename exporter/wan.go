@@ -15,19 +15,16 @@
 package exporter
 
 import (
+	"context"
 	"strings"
 
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
 )
 
 var (
-	ftthState = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "", "wan_ftth_state"),
-		"LinkState of the GEth FTTH port",
-		nil, nil,
-	)
 	txBytesWan = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "wan_transmitted_bytes"),
 		"TX bytes",
@@ -99,6 +96,31 @@ var (
 		"RX bandwith available",
 		nil, nil,
 	)
+	internetStateWan = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_internet_state"),
+		"State of the Internet connection (2 is up)",
+		nil, nil,
+	)
+	linkUpWan = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_link_up"),
+		"Is the WAN link up",
+		[]string{"type"}, nil,
+	)
+	mtuWan = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_mtu"),
+		"MTU of the WAN",
+		nil, nil,
+	)
+	ipInfoWan = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ip_info"),
+		"IP configuration of the WAN",
+		[]string{"address", "gateway", "dnsservers", "ip6state", "ip6prefix"}, nil,
+	)
+	ipChangesWan = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_ip_changes_total"),
+		"Number of changes of the public IPv4 address",
+		nil, nil,
+	)
 	diagnosticsMinWan = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "wan_diagnostics_min"),
 		"Minimum response Time",
@@ -131,8 +153,40 @@ var (
 	)
 )
 
+func init() {
+	registerCollector("wan", defaultEnabled, NewWanCollector)
+}
+
+type wanCollector struct {
+	logger log.Logger
+}
+
+// NewWanCollector returns a new Collector exposing wan metrics.
+func NewWanCollector(logger log.Logger) Collector {
+	return &wanCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *wanCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeWanMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *wanCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetWanMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeWanMetrics(ch, *metrics)
+	ch <- prometheus.MustNewConstMetric(
+		ipChangesWan, prometheus.CounterValue, float64(client.WanIPChanges()),
+	)
+	return nil
+}
+
 func describeWanMetrics(ch chan<- *prometheus.Desc) {
-	ch <- ftthState
 	ch <- txBytesWan
 	ch <- txPacketsWan
 	ch <- txPacketsErrorsWan
@@ -147,6 +201,11 @@ func describeWanMetrics(ch chan<- *prometheus.Desc) {
 	ch <- rxLineOccupationWan
 	ch <- rxBandwidthWan
 	ch <- rxBandwidthMaxWan
+	ch <- internetStateWan
+	ch <- linkUpWan
+	ch <- mtuWan
+	ch <- ipInfoWan
+	ch <- ipChangesWan
 	ch <- diagnosticsMinWan
 	ch <- diagnosticsMaxWan
 	ch <- diagnosticsAvgWan
@@ -156,53 +215,66 @@ func describeWanMetrics(ch chan<- *prometheus.Desc) {
 }
 
 func storeWanMetrics(ch chan<- prometheus.Metric, metrics bbox.WanMetrics) {
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Bytes), txBytesWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packets), txPacketsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packetserrors), txPacketsErrorsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packetsdiscards), txPacketsDiscardsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Occupation), txLineOccupationWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Bandwidth), txBandwidthWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.MaxBandwidth), txBandwidthMaxWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Bytes), rxBytesWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packets), rxPacketsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packetserrors), rxPacketsErrorsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packetsdiscards), rxPacketsDiscardsWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Occupation), rxLineOccupationWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Bandwidth), rxBandwidthWan)
-	storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.MaxBandwidth), rxBandwidthMaxWan)
-
-	for _, val := range metrics.DiagnosticsStatistics[0].Diags.DNS {
-		if val.Tries > 0 {
-			storeMetric(ch, float64(val.Min), diagnosticsMinWan, "dns")
-			storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "dns")
-			storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "dns")
-			break
-		}
+	if len(metrics.IPInformations) > 0 {
+		storeWanIPInformations(ch, metrics.IPInformations[0])
 	}
-	for _, val := range metrics.DiagnosticsStatistics[0].Diags.Ping {
-		if val.Tries > 0 {
-			storeMetric(ch, float64(val.Min), diagnosticsMinWan, "ping")
-			storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "ping")
-			storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "ping")
-			break
-		}
+	if len(metrics.IPStatistics) > 0 {
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Bytes), txBytesWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packets), txPacketsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packetserrors), txPacketsErrorsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Packetsdiscards), txPacketsDiscardsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Occupation), txLineOccupationWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.Bandwidth), txBandwidthWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Tx.MaxBandwidth), txBandwidthMaxWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Bytes), rxBytesWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packets), rxPacketsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packetserrors), rxPacketsErrorsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Packetsdiscards), rxPacketsDiscardsWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Occupation), rxLineOccupationWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.Bandwidth), rxBandwidthWan)
+		storeMetric(ch, float64(metrics.IPStatistics[0].WAN.IP.Stats.Rx.MaxBandwidth), rxBandwidthMaxWan)
 	}
-	for _, val := range metrics.DiagnosticsStatistics[0].Diags.HTTP {
-		if val.Tries > 0 {
-			storeMetric(ch, float64(val.Min), diagnosticsMinWan, "http")
-			storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "http")
-			storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "http")
-			break
+	if len(metrics.DiagnosticsStatistics) > 0 {
+		for _, val := range metrics.DiagnosticsStatistics[0].Diags.DNS {
+			if val.Tries > 0 {
+				storeMetric(ch, float64(val.Min), diagnosticsMinWan, "dns")
+				storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "dns")
+				storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "dns")
+				break
+			}
+		}
+		for _, val := range metrics.DiagnosticsStatistics[0].Diags.Ping {
+			if val.Tries > 0 {
+				storeMetric(ch, float64(val.Min), diagnosticsMinWan, "ping")
+				storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "ping")
+				storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "ping")
+				break
+			}
+		}
+		for _, val := range metrics.DiagnosticsStatistics[0].Diags.HTTP {
+			if val.Tries > 0 {
+				storeMetric(ch, float64(val.Min), diagnosticsMinWan, "http")
+				storeMetric(ch, float64(val.Max), diagnosticsMaxWan, "http")
+				storeMetric(ch, float64(val.Average), diagnosticsAvgWan, "http")
+				break
+			}
 		}
 	}
 }
 
-func storeWanFtthMetric(ch chan<- prometheus.Metric, metric string) {
-	fftStateValue := float64(0)
-	if strings.ToUpper(metric) == "UP" {
-		fftStateValue = float64(1)
+func storeWanIPInformations(ch chan<- prometheus.Metric, informations bbox.WanIPInformations) {
+	wan := informations.Wan
+	storeMetric(ch, float64(wan.Internet.State), internetStateWan)
+	linkUp := 0.0
+	if strings.ToUpper(wan.Link.State) == "UP" {
+		linkUp = 1.0
 	}
-	ch <- prometheus.MustNewConstMetric(
-		ftthState, prometheus.GaugeValue, fftStateValue,
-	)
+	storeMetric(ch, linkUp, linkUpWan, strings.ToLower(wan.Link.Type))
+	storeMetric(ch, float64(wan.IP.Mtu), mtuWan)
+
+	prefixes := make([]string, 0, len(wan.IP.IP6Prefix))
+	for _, prefix := range wan.IP.IP6Prefix {
+		prefixes = append(prefixes, prefix.Prefix)
+	}
+	storeMetric(ch, 1.0, ipInfoWan, wan.IP.Address, wan.IP.Gateway, wan.IP.Dnsservers, wan.IP.IP6State, strings.Join(prefixes, ","))
 }
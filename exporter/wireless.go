@@ -15,12 +15,57 @@
 package exporter
 
 import (
+	"context"
+	"strconv"
+
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nlamirault/bbox_exporter/bbox"
 )
 
 var (
+	wirelessRadioEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_radio_enabled"),
+		"Is the WIFI radio enabled",
+		[]string{"band"}, nil,
+	)
+	wirelessRadioInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_radio_info"),
+		"WIFI standard of the radio",
+		[]string{"band", "standard"}, nil,
+	)
+	wirelessChannel = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_channel"),
+		"Current channel of the WIFI radio",
+		[]string{"band"}, nil,
+	)
+	wirelessChannelWidth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_channel_width_mhz"),
+		"Channel width of the WIFI radio in MHz",
+		[]string{"band"}, nil,
+	)
+	wirelessDFS = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_dfs_enabled"),
+		"Is the Dynamic Frequency Selection enabled",
+		[]string{"band"}, nil,
+	)
+	wirelessTxPower = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_transmit_power"),
+		"Transmit power of the WIFI radio",
+		[]string{"band"}, nil,
+	)
+	wirelessSSIDEnabled = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_ssid_enabled"),
+		"Is the WIFI network enabled",
+		[]string{"band", "ssid"}, nil,
+	)
+	wirelessSSIDInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wireless_ssid_info"),
+		"Security settings of the WIFI network",
+		[]string{"band", "ssid", "security", "encryption", "hidden"}, nil,
+	)
+
 	txBytesWireless = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, "", "wireless_transmitted_bytes"),
 		"TX bytes",
@@ -64,8 +109,45 @@ var (
 	)
 )
 
+func init() {
+	registerCollector("wireless", defaultEnabled, NewWirelessCollector)
+}
+
+type wirelessCollector struct {
+	logger log.Logger
+}
+
+// NewWirelessCollector returns a new Collector exposing wireless metrics.
+func NewWirelessCollector(logger log.Logger) Collector {
+	return &wirelessCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *wirelessCollector) Describe(ch chan<- *prometheus.Desc) {
+	describeWirelessMetrics(ch)
+}
+
+// Update implements the Collector interface.
+func (c *wirelessCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	metrics, err := client.GetWirelessMetrics(ctx)
+	if metrics != nil {
+		storeWirelessMetrics(ch, *metrics)
+	}
+	return err
+}
+
 func describeWirelessMetrics(ch chan<- *prometheus.Desc) {
 	ch <- hosts
+	ch <- wirelessRadioEnabled
+	ch <- wirelessRadioInfo
+	ch <- wirelessChannel
+	ch <- wirelessChannelWidth
+	ch <- wirelessDFS
+	ch <- wirelessTxPower
+	ch <- wirelessSSIDEnabled
+	ch <- wirelessSSIDInfo
 	ch <- txBytesWireless
 	ch <- txPacketsWireless
 	ch <- txPacketsErrorsWireless
@@ -77,20 +159,55 @@ func describeWirelessMetrics(ch chan<- *prometheus.Desc) {
 }
 
 func storeWirelessMetrics(ch chan<- prometheus.Metric, metrics bbox.WirelessMetrics) {
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Tx.Bytes), txBytesWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Tx.Packets), txPacketsWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Tx.Packetserrors), txPacketsErrorsWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Tx.Packetsdiscards), txPacketsDiscardsWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Rx.Bytes), rxBytesWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Rx.Packets), rxPacketsWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Rx.Packetserrors), rxPacketsErrorsWireless, "5ghz")
-	// storeMetric(ch, float64(metrics.Wireless5GhzStatistics[0].Wireless.SSID.Stats.Rx.Packetsdiscards), rxPacketsDiscardsWireless, "5ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Tx.Bytes), txBytesWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Tx.Packets), txPacketsWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Tx.Packetserrors), txPacketsErrorsWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Tx.Packetsdiscards), txPacketsDiscardsWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Rx.Bytes), rxBytesWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Rx.Packets), rxPacketsWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Rx.Packetserrors), rxPacketsErrorsWireless, "24ghz")
-	storeMetric(ch, float64(metrics.Wireless24GhzStatistics[0].Wireless.SSID.Stats.Rx.Packetsdiscards), rxPacketsDiscardsWireless, "24ghz")
+	if len(metrics.Informations) > 0 {
+		storeWirelessInformations(ch, metrics.Informations[0])
+	}
+	storeWirelessStatistics(ch, metrics.Wireless5GhzStatistics, "5ghz")
+	storeWirelessStatistics(ch, metrics.Wireless24GhzStatistics, "24ghz")
+}
+
+// storeWirelessStatistics stores the statistics of a band, unless the band
+// is disabled or missing.
+func storeWirelessStatistics(ch chan<- prometheus.Metric, statistics []bbox.WirelessStatistics, frequency string) {
+	if len(statistics) == 0 {
+		return
+	}
+	stats := statistics[0].Wireless.SSID.Stats
+	storeMetric(ch, float64(stats.Tx.Bytes), txBytesWireless, frequency)
+	storeMetric(ch, float64(stats.Tx.Packets), txPacketsWireless, frequency)
+	storeMetric(ch, float64(stats.Tx.Packetserrors), txPacketsErrorsWireless, frequency)
+	storeMetric(ch, float64(stats.Tx.Packetsdiscards), txPacketsDiscardsWireless, frequency)
+	storeMetric(ch, float64(stats.Rx.Bytes), rxBytesWireless, frequency)
+	storeMetric(ch, float64(stats.Rx.Packets), rxPacketsWireless, frequency)
+	storeMetric(ch, float64(stats.Rx.Packetserrors), rxPacketsErrorsWireless, frequency)
+	storeMetric(ch, float64(stats.Rx.Packetsdiscards), rxPacketsDiscardsWireless, frequency)
+}
+
+// storeWirelessInformations stores the configuration of the radios and SSID.
+// The channel is the current one, as the configured one is 0 in automatic mode.
+func storeWirelessInformations(ch chan<- prometheus.Metric, informations bbox.WirelessInformations) {
+	for band, radio := range informations.Wireless.Radio {
+		label := wirelessBand(band)
+		channel := radio.CurrentChannel
+		if channel == 0 {
+			channel = radio.Channel
+		}
+		storeMetric(ch, float64(radio.Enable), wirelessRadioEnabled, label)
+		storeMetric(ch, 1.0, wirelessRadioInfo, label, radio.Standard)
+		storeMetric(ch, float64(channel), wirelessChannel, label)
+		storeMetric(ch, float64(radio.HTBW), wirelessChannelWidth, label)
+		storeMetric(ch, float64(radio.DFS), wirelessDFS, label)
+		storeMetric(ch, float64(radio.TxPower), wirelessTxPower, label)
+	}
+	for band, ssid := range informations.Wireless.SSID {
+		label := wirelessBand(band)
+		storeMetric(ch, float64(ssid.Enable), wirelessSSIDEnabled, label, ssid.ID)
+		storeMetric(ch, 1.0, wirelessSSIDInfo, label, ssid.ID,
+			ssid.Security.Protocol, ssid.Security.Encryption, strconv.FormatBool(ssid.Hidden != 0))
+	}
+}
+
+// wirelessBand returns the label of a band of the Bbox API, like the statistics: "24ghz" or "5ghz".
+func wirelessBand(band string) string {
+	return band + "ghz"
 }
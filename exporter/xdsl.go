@@ -0,0 +1,152 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nlamirault/bbox_exporter/bbox"
+)
+
+var (
+	xdslConnected = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_connected"),
+		"Is the xDSL line synchronized",
+		nil, nil,
+	)
+	xdslInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_info"),
+		"Modulation and profile of the xDSL line",
+		[]string{"modulation", "profile"}, nil,
+	)
+	xdslShowtime = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_showtime_seconds"),
+		"Time since the xDSL line is synchronized",
+		nil, nil,
+	)
+	xdslSyncRate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_sync_rate_kbps"),
+		"Synchronization rate of the xDSL line",
+		[]string{"direction"}, nil,
+	)
+	xdslAttainableRate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_attainable_rate_kbps"),
+		"Maximum attainable rate of the xDSL line",
+		[]string{"direction"}, nil,
+	)
+	xdslSNRMargin = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_snr_margin_db"),
+		"Signal to noise ratio margin of the xDSL line",
+		[]string{"direction"}, nil,
+	)
+	xdslAttenuation = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_attenuation_db"),
+		"Attenuation of the xDSL line",
+		[]string{"direction"}, nil,
+	)
+	xdslErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wan_xdsl_errors_total"),
+		"CRC, FEC and HEC errors of the xDSL line",
+		[]string{"side", "type"}, nil,
+	)
+)
+
+func init() {
+	registerCollector("xdsl", defaultEnabled, NewXdslCollector)
+}
+
+type xdslCollector struct {
+	logger log.Logger
+}
+
+// NewXdslCollector returns a new Collector exposing xDSL line metrics.
+// It only queries a Bbox using ADSL or VDSL.
+func NewXdslCollector(logger log.Logger) Collector {
+	return &xdslCollector{
+		logger: logger,
+	}
+}
+
+// Describe implements the Collector interface.
+func (c *xdslCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- xdslConnected
+	ch <- xdslInfo
+	ch <- xdslShowtime
+	ch <- xdslSyncRate
+	ch <- xdslAttainableRate
+	ch <- xdslSNRMargin
+	ch <- xdslAttenuation
+	ch <- xdslErrors
+}
+
+// Update implements the Collector interface.
+func (c *xdslCollector) Update(ctx context.Context, client *bbox.Client, ch chan<- prometheus.Metric) error {
+	links, err := client.GetWanLinks(ctx)
+	if err != nil {
+		return err
+	}
+	if !links.ADSL && !links.VDSL {
+		level.Debug(c.logger).Log("msg", "Bbox doesn't use xDSL")
+		return nil
+	}
+	metrics, err := client.GetXdslMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	storeXdslMetrics(c.logger, ch, *metrics)
+	return nil
+}
+
+func storeXdslMetrics(logger log.Logger, ch chan<- prometheus.Metric, metrics bbox.XdslMetrics) {
+	if len(metrics.Informations) > 0 {
+		xdsl := metrics.Informations[0].WAN.Xdsl
+		connected := 0.0
+		if xdsl.State == "Connected" {
+			connected = 1.0
+		}
+		storeMetric(ch, connected, xdslConnected)
+		storeMetric(ch, 1.0, xdslInfo, xdsl.Modulation, xdsl.Profile)
+		storeMetric(ch, float64(xdsl.Showtime), xdslShowtime)
+		for direction, line := range map[string]bbox.XdslLineDirection{"up": xdsl.Up, "down": xdsl.Down} {
+			storeMetric(ch, float64(line.Bitrates), xdslSyncRate, direction)
+			storeMetric(ch, float64(line.MaxBitrates), xdslAttainableRate, direction)
+			storeMetric(ch, float64(line.Noise), xdslSNRMargin, direction)
+			storeMetric(ch, float64(line.Attenuation), xdslAttenuation, direction)
+		}
+	} else {
+		level.Warn(logger).Log("msg", "No informations for xDSL")
+	}
+	if len(metrics.Statistics) > 0 {
+		stats := metrics.Statistics[0].WAN.Xdsl.Stats
+		storeXdslErrors(ch, float64(stats.LocalCRC), "local", "crc")
+		storeXdslErrors(ch, float64(stats.LocalFEC), "local", "fec")
+		storeXdslErrors(ch, float64(stats.LocalHEC), "local", "hec")
+		storeXdslErrors(ch, float64(stats.RemoteCRC), "remote", "crc")
+		storeXdslErrors(ch, float64(stats.RemoteFEC), "remote", "fec")
+		storeXdslErrors(ch, float64(stats.RemoteHEC), "remote", "hec")
+	} else {
+		level.Warn(logger).Log("msg", "No statistics for xDSL")
+	}
+}
+
+// storeXdslErrors stores a count of errors of the line, which only increases
+// until the line is synchronized again.
+func storeXdslErrors(ch chan<- prometheus.Metric, value float64, side string, errorType string) {
+	ch <- prometheus.MustNewConstMetric(xdslErrors, prometheus.CounterValue, value, side, errorType)
+}
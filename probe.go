@@ -0,0 +1,141 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/nlamirault/bbox_exporter/config"
+	"github.com/nlamirault/bbox_exporter/exporter"
+)
+
+const defaultModule = "default"
+
+// scrapeContext returns the context of a scrape, whose deadline is the
+// timeout given by Prometheus minus the offset.
+func scrapeContext(r *http.Request, offset float64) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse timeout from Prometheus header: %s", err)
+	}
+	if seconds > offset {
+		seconds -= offset
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(seconds*float64(time.Second)))
+	return ctx, cancel, nil
+}
+
+// metricsHandler exposes the metrics of the exporter, if any, along with the
+// ones of the default registry.
+func metricsHandler(w http.ResponseWriter, r *http.Request, exp *exporter.Exporter, logger log.Logger) {
+	ctx, cancel, err := scrapeContext(r, *timeoutOffset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		level.Error(logger).Log("msg", "Invalid scrape timeout", "err", err)
+		return
+	}
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	if exp != nil {
+		registry.MustRegister(exp.WithContext(ctx))
+	}
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+	h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
+
+// probeHandler scrape the Bbox given by the target parameter. The target is
+// either the name of a target of the configuration file, or an endpoint queried
+// using the settings of the module parameter if the module allows it, as the
+// password of the module is sent to the endpoint.
+func probeHandler(w http.ResponseWriter, r *http.Request, conf *config.Config, cache *clientCache, logger log.Logger) {
+	params := r.URL.Query()
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+		return
+	}
+
+	var endpoint, key string
+	var module config.Module
+	t, configured := conf.Targets[target]
+	if configured {
+		endpoint = t.Endpoint
+		module = t.Module
+		key = target
+		logger = log.With(logger, "target", target)
+	} else {
+		moduleName := params.Get("module")
+		if moduleName == "" {
+			moduleName = defaultModule
+		}
+		m, ok := conf.Modules[moduleName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown module %q", moduleName), http.StatusBadRequest)
+			level.Debug(logger).Log("msg", "Unknown module", "module", moduleName)
+			return
+		}
+		if !m.AllowUnconfiguredTargets {
+			http.Error(w, fmt.Sprintf("Target %q is not configured and module %q doesn't allow unconfigured targets", target, moduleName), http.StatusForbidden)
+			level.Debug(logger).Log("msg", "Unconfigured target refused", "target", target, "module", moduleName)
+			return
+		}
+		endpoint = target
+		module = m
+		key = fmt.Sprintf("%s?module=%s", target, moduleName)
+		logger = log.With(logger, "target", target, "module", moduleName)
+	}
+
+	client, err := cache.get(key, endpoint, module, configured, logger)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid target %q: %s", target, err), http.StatusBadRequest)
+		level.Error(logger).Log("msg", "Can't create client", "err", err)
+		return
+	}
+	exp, err := exporter.NewExporter(client, module.Collectors, logger)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid module for target %q: %s", target, err), http.StatusBadRequest)
+		level.Error(logger).Log("msg", "Can't create exporter", "err", err)
+		return
+	}
+
+	ctx, cancel, err := scrapeContext(r, *timeoutOffset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		level.Error(logger).Log("msg", "Invalid scrape timeout", "err", err)
+		return
+	}
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exp.WithContext(ctx))
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
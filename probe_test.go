@@ -0,0 +1,79 @@
+// Copyright (C) 2021 Nicolas Lamirault <nicolas.lamirault@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	promconfig "github.com/prometheus/common/config"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/nlamirault/bbox_exporter/bbox/bboxtest"
+	"github.com/nlamirault/bbox_exporter/config"
+)
+
+func TestMain(m *testing.M) {
+	// Flags have their default value once parsed, like --scrape.max-parallelism
+	if _, err := kingpin.CommandLine.Parse([]string{}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func probe(conf *config.Config, cache *clientCache, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/probe?target="+url.QueryEscape(target), nil)
+	probeHandler(w, r, conf, cache, log.NewNopLogger())
+	return w
+}
+
+func TestProbeUnconfiguredTarget(t *testing.T) {
+	server := bboxtest.NewServer()
+	defer server.Close()
+	module := config.Module{
+		Password:   bboxtest.DefaultPassword,
+		TLSConfig:  promconfig.TLSConfig{InsecureSkipVerify: true},
+		Collectors: []string{"device"},
+	}
+	conf := &config.Config{Modules: map[string]config.Module{defaultModule: module}}
+	cache := newClientCache(2)
+
+	// The password of the module isn't sent to any endpoint by default
+	if w := probe(conf, cache, server.URL); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if server.Logins() != 0 || len(cache.clients) != 0 {
+		t.Errorf("unconfigured target probed")
+	}
+
+	module.AllowUnconfiguredTargets = true
+	conf.Modules[defaultModule] = module
+	if w := probe(conf, cache, server.URL); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if server.Logins() != 1 {
+		t.Errorf("expected 1 login, got %d", server.Logins())
+	}
+	w := probe(conf, cache, "http://192.168.1.254")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "must use https") {
+		t.Errorf("expected status %d for an http endpoint, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+}